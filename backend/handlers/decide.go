@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/yuuhikaze/rgxr/logic"
+	"net/http"
+)
+
+// PairRequest names the two FAs of a decision procedure, either by UUID or
+// inline; UUIDs come first when both are given
+type PairRequest struct {
+	UUIDs []string    `json:"uuids,omitempty"`
+	FAs   []*logic.FA `json:"fas,omitempty"`
+}
+
+// EquivalenceHandler decides whether two FAs accept the same language
+func EquivalenceHandler(w http.ResponseWriter, r *http.Request) {
+	a, b, ok := decodePair(w, r)
+	if !ok {
+		return
+	}
+
+	result, err := logic.Equivalent(a, b)
+	if err != nil {
+		http.Error(w, "Equivalence error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// InclusionHandler decides whether the language of the first FA is included
+// in that of the second
+func InclusionHandler(w http.ResponseWriter, r *http.Request) {
+	a, b, ok := decodePair(w, r)
+	if !ok {
+		return
+	}
+
+	result, err := logic.Subset(a, b)
+	if err != nil {
+		http.Error(w, "Inclusion error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// DisjointnessHandler decides whether two FAs accept no common string
+func DisjointnessHandler(w http.ResponseWriter, r *http.Request) {
+	a, b, ok := decodePair(w, r)
+	if !ok {
+		return
+	}
+
+	result, err := logic.Disjoint(a, b)
+	if err != nil {
+		http.Error(w, "Disjointness error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// decodePair reads a PairRequest and loads its FAs, writing the error response
+// itself when that fails
+func decodePair(w http.ResponseWriter, r *http.Request) (*logic.FA, *logic.FA, bool) {
+	var req PairRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON: "+err.Error(), http.StatusBadRequest)
+		return nil, nil, false
+	}
+
+	if len(req.UUIDs)+len(req.FAs) != 2 {
+		http.Error(w, "Need exactly two FAs, given as uuids or fas", http.StatusBadRequest)
+		return nil, nil, false
+	}
+
+	// Load FAs from PostgREST API
+	var automata []*logic.FA
+	for _, uuid := range req.UUIDs {
+		fa, err := loadFAFromAPI(uuid)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Error loading FA %s: %v", uuid, err), http.StatusInternalServerError)
+			return nil, nil, false
+		}
+		automata = append(automata, fa)
+	}
+	for _, fa := range req.FAs {
+		if fa == nil {
+			http.Error(w, "Inline FA must not be null", http.StatusBadRequest)
+			return nil, nil, false
+		}
+		automata = append(automata, fa)
+	}
+
+	return automata[0], automata[1], true
+}
@@ -0,0 +1,171 @@
+package handlers
+
+import (
+	"encoding/json"
+	"github.com/yuuhikaze/rgxr/logic"
+	"math/big"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Bounds on the words /words and /sample produce, which take time and memory
+// linear in both
+const (
+	maxWordLength = 1000
+	maxWordCount  = 10000
+)
+
+// AnalyzeHandler reports emptiness, finiteness, universality and word
+// lengths of the language of an FA
+func AnalyzeHandler(w http.ResponseWriter, r *http.Request) {
+	uuid := r.URL.Query().Get("uuid")
+	if uuid == "" {
+		http.Error(w, "Missing uuid parameter", http.StatusBadRequest)
+		return
+	}
+
+	fa, err := loadFAFromAPI(uuid)
+	if err != nil {
+		http.Error(w, "Error loading FA: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	analysis, err := logic.Analyze(fa)
+	if err != nil {
+		http.Error(w, "Analysis error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(analysis)
+}
+
+// WordsResponse lists accepted words in shortlex order along with the number
+// of accepted words of every length up to the requested maximum
+type WordsResponse struct {
+	Words         []string   `json:"words"`
+	CountByLength []*big.Int `json:"count_by_length"`
+}
+
+// WordsHandler enumerates the words accepted by an FA
+func WordsHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	uuid := query.Get("uuid")
+	if uuid == "" {
+		http.Error(w, "Missing uuid parameter", http.StatusBadRequest)
+		return
+	}
+
+	maxLen, err := intParameter(query.Get("max_len"), 10)
+	if err != nil || maxLen < 0 || maxLen > maxWordLength {
+		http.Error(w, "Invalid max_len parameter: need 0 to "+strconv.Itoa(maxWordLength), http.StatusBadRequest)
+		return
+	}
+	limit, err := intParameter(query.Get("limit"), 100)
+	if err != nil || limit <= 0 || limit > maxWordCount {
+		http.Error(w, "Invalid limit parameter: need 1 to "+strconv.Itoa(maxWordCount), http.StatusBadRequest)
+		return
+	}
+
+	fa, err := loadFAFromAPI(uuid)
+	if err != nil {
+		http.Error(w, "Error loading FA: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	words, err := logic.Enumerate(fa, maxLen, limit)
+	if err != nil {
+		http.Error(w, "Enumeration error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	counts, err := logic.CountByLength(fa, maxLen)
+	if err != nil {
+		http.Error(w, "Counting error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	resp := WordsResponse{
+		Words:         words,
+		CountByLength: counts,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// SampleRequest asks for Count accepted words of the given Length drawn
+// uniformly at random, plus as many rejected ones when Rejected is set. A
+// random seed is chosen when Seed is omitted.
+type SampleRequest struct {
+	UUID     string `json:"uuid"`
+	Length   int    `json:"length"`
+	Count    int    `json:"count"`
+	Rejected bool   `json:"rejected"`
+	Seed     *int64 `json:"seed,omitempty"`
+}
+
+// SampleResponse holds the drawn words and the seed that reproduces them
+type SampleResponse struct {
+	Accepted []string `json:"accepted"`
+	Rejected []string `json:"rejected,omitempty"`
+	Seed     int64    `json:"seed"`
+}
+
+// SampleHandler draws random accepted (and optionally rejected) strings of an FA
+func SampleHandler(w http.ResponseWriter, r *http.Request) {
+	var req SampleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if req.UUID == "" {
+		http.Error(w, "Missing uuid field", http.StatusBadRequest)
+		return
+	}
+	if req.Length < 0 || req.Length > maxWordLength {
+		http.Error(w, "Invalid length: need 0 to "+strconv.Itoa(maxWordLength), http.StatusBadRequest)
+		return
+	}
+	if req.Count <= 0 || req.Count > maxWordCount {
+		http.Error(w, "Invalid count: need 1 to "+strconv.Itoa(maxWordCount), http.StatusBadRequest)
+		return
+	}
+
+	fa, err := loadFAFromAPI(req.UUID)
+	if err != nil {
+		http.Error(w, "Error loading FA: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	seed := time.Now().UnixNano()
+	if req.Seed != nil {
+		seed = *req.Seed
+	}
+
+	sampler, err := logic.NewSampler(fa, seed)
+	if err != nil {
+		http.Error(w, "Sampling error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	resp := SampleResponse{
+		Accepted: sampler.Accepted(req.Length, req.Count),
+		Seed:     seed,
+	}
+	if req.Rejected {
+		resp.Rejected = sampler.Rejected(req.Length, req.Count)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// intParameter parses an optional integer query parameter
+func intParameter(value string, fallback int) (int, error) {
+	if value == "" {
+		return fallback, nil
+	}
+	return strconv.Atoi(value)
+}
@@ -6,12 +6,16 @@ import (
 	"github.com/yuuhikaze/rgxr/logic"
 	"io"
 	"net/http"
+	"strings"
 )
 
-// BooleanRequest represents request for FA intersection
+// BooleanRequest represents request for FA boolean operations. When Expression
+// is set (e.g. "A ∩ ¬B ∪ C", operands named by position in UUIDs) it takes
+// precedence over Mode.
 type BooleanRequest struct {
-	UUIDs []string          `json:"uuids"`
-	Mode  logic.BooleanMode `json:"mode"`
+	UUIDs      []string          `json:"uuids"`
+	Mode       logic.BooleanMode `json:"mode"`
+	Expression string            `json:"expression,omitempty"`
 }
 
 // BooleanHandler handles deterministic boolean operations of multiple FAs
@@ -22,7 +26,12 @@ func BooleanHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if len(req.UUIDs) < 2 {
+	if req.Expression != "" {
+		if len(req.UUIDs) == 0 {
+			http.Error(w, "Need at least one FA for expression", http.StatusBadRequest)
+			return
+		}
+	} else if len(req.UUIDs) < 2 {
 		http.Error(w, fmt.Sprintf("Need at least two FAs for %s", req.Mode), http.StatusBadRequest)
 		return
 	}
@@ -39,7 +48,13 @@ func BooleanHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Perform boolean operation
-	result, err := logic.PerformBoolean(automata, req.Mode)
+	var result *logic.FA
+	var err error
+	if req.Expression != "" {
+		result, err = logic.PerformBooleanExpression(automata, req.Expression)
+	} else {
+		result, err = logic.PerformBoolean(automata, req.Mode)
+	}
 	if err != nil {
 		http.Error(w, "Boolean error: "+err.Error(), http.StatusInternalServerError)
 		return
@@ -49,6 +64,13 @@ func BooleanHandler(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(result)
 }
 
+// CombinationResponse is an FA whose states were renumbered, along with the
+// origin of each state; operands are numbered by their position in UUIDs
+type CombinationResponse struct {
+	*logic.FA
+	Provenance logic.Provenance `json:"provenance,omitempty"`
+}
+
 // NBooleanHandler handles non-deterministic boolean operations of multiple FAs
 func NBooleanHandler(w http.ResponseWriter, r *http.Request) {
 	var req BooleanRequest
@@ -74,17 +96,24 @@ func NBooleanHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Perform boolean operation
-	result, err := logic.NPerformBoolean(automata, req.Mode)
+	result, provenance, err := logic.NPerformBoolean(automata, req.Mode)
 	if err != nil {
 		http.Error(w, "Boolean error: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(result)
+	json.NewEncoder(w).Encode(CombinationResponse{FA: result, Provenance: provenance})
+}
+
+// NFAToDFAResponse is a DFA along with the subset construction steps that
+// produced it, when asked for
+type NFAToDFAResponse struct {
+	*logic.FA
+	Trace *logic.SubsetTrace `json:"trace,omitempty"`
 }
 
-// NFAToDFAHandler converts NFA to DFA
+// NFAToDFAHandler converts NFA to DFA, with the subset construction steps if ?trace=true
 func NFAToDFAHandler(w http.ResponseWriter, r *http.Request) {
 	uuid := r.URL.Query().Get("uuid")
 	if uuid == "" {
@@ -98,17 +127,36 @@ func NFAToDFAHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	dfa, err := logic.NFAToDFA(nfa)
+	var response NFAToDFAResponse
+	if r.URL.Query().Get("trace") == "true" {
+		response.FA, response.Trace, err = logic.NFAToDFATrace(nfa)
+	} else {
+		response.FA, err = logic.NFAToDFA(nfa)
+	}
 	if err != nil {
 		http.Error(w, "NFA to DFA conversion error: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(dfa)
+	json.NewEncoder(w).Encode(response)
+}
+
+// FAToRegexResponse holds the regex produced by state elimination before and
+// after simplification, and optionally the sizes every elimination order gives
+// and the generalized NFA after every elimination
+type FAToRegexResponse struct {
+	Raw        string                  `json:"raw"`
+	Simplified string                  `json:"simplified"`
+	Sizes      []logic.EliminationSize `json:"sizes,omitempty"`
+	Steps      []logic.EliminationStep `json:"steps,omitempty"`
 }
 
-// FAToRegexHandler converts FA to regular expression
+// FAToRegexHandler converts FA to a simplified regular expression, eliminating
+// states in the ?order=array|edges|weight|custom given (custom takes the
+// comma-separated ?states=). With ?raw=true, ?sizes=true or ?trace=true it
+// returns JSON with the raw regex too, plus the result of every order or the
+// generalized NFA after every elimination respectively
 func FAToRegexHandler(w http.ResponseWriter, r *http.Request) {
 	uuid := r.URL.Query().Get("uuid")
 	if uuid == "" {
@@ -116,28 +164,70 @@ func FAToRegexHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	var custom []string
+	if states := r.URL.Query().Get("states"); states != "" {
+		custom = strings.Split(states, ",")
+	}
+	order := logic.EliminationOrder(r.URL.Query().Get("order"))
+	if order == "" {
+		order = logic.ArrayOrder
+		if custom != nil {
+			order = logic.CustomOrder
+		}
+	}
+
 	fa, err := loadFAFromAPI(uuid)
 	if err != nil {
 		http.Error(w, "Error loading FA: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	regex, err := logic.FAToRegex(fa)
+	// The trace is only recorded when asked for, as it copies the whole
+	// generalized NFA after every elimination
+	trace := r.URL.Query().Get("trace") == "true"
+	var regex *logic.Regex
+	var steps []logic.EliminationStep
+	if trace {
+		regex, steps, err = logic.EliminationTrace(fa, order, custom)
+	} else {
+		regex, err = logic.RegexFromFAWith(fa, order, custom)
+	}
 	if err != nil {
 		http.Error(w, "FA to regex conversion error: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
 
+	raw, sizes := r.URL.Query().Get("raw") == "true", r.URL.Query().Get("sizes") == "true"
+	if raw || sizes || trace {
+		response := FAToRegexResponse{
+			Raw:        regex.String(),
+			Simplified: regex.Simplify().String(),
+			Steps:      steps,
+		}
+		if sizes {
+			response.Sizes, err = logic.CompareEliminationOrders(fa, custom)
+			if err != nil {
+				http.Error(w, "FA to regex conversion error: "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
 	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
-	w.Write([]byte(regex))
+	w.Write([]byte(regex.Simplify().String()))
 }
 
 // RegexToNFARequest represents request for regex to NFA conversion
 type RegexToNFARequest struct {
-	Regex string `json:"regex"`
+	Regex     string   `json:"regex"`
+	Algorithm string   `json:"algorithm,omitempty"` // thompson (default), glushkov or antimirov
+	Alphabet  []string `json:"alphabet,omitempty"`  // symbols of the result, which classes and wildcards range over
 }
 
-// RegexToNFAHandler converts regular expression to NFA
+// RegexToNFAHandler converts regular expression to NFA with the requested construction
 func RegexToNFAHandler(w http.ResponseWriter, r *http.Request) {
 	var req RegexToNFARequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -150,7 +240,12 @@ func RegexToNFAHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	nfa, err := logic.RegexToNFA(req.Regex)
+	algorithm := logic.RegexAlgorithm(req.Algorithm)
+	if algorithm == "" {
+		algorithm = logic.Thompson
+	}
+
+	nfa, err := logic.RegexToNFAWith(req.Regex, algorithm, req.Alphabet)
 	if err != nil {
 		http.Error(w, "Regex to NFA conversion error: "+err.Error(), http.StatusInternalServerError)
 		return
@@ -160,7 +255,90 @@ func RegexToNFAHandler(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(nfa)
 }
 
-// MinimizeDFAHandler minimizes a DFA
+// RegexToDFAResponse is a DFA built from a regex along with the derivative
+// every state stands for
+type RegexToDFAResponse struct {
+	*logic.FA
+	Derivatives map[string]string `json:"derivatives"`
+}
+
+// RegexToDFARequest represents request for regex to DFA conversion, which has
+// no choice of construction
+type RegexToDFARequest struct {
+	Regex     string   `json:"regex"`
+	Algorithm string   `json:"algorithm,omitempty"` // rejected if given, as for /regex-to-nfa
+	Alphabet  []string `json:"alphabet,omitempty"`  // symbols of the result, which classes and wildcards range over
+}
+
+// RegexToDFAHandler converts regular expression to DFA using Brzozowski derivatives
+func RegexToDFAHandler(w http.ResponseWriter, r *http.Request) {
+	var req RegexToDFARequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if req.Regex == "" {
+		http.Error(w, "Missing regex field", http.StatusBadRequest)
+		return
+	}
+
+	if req.Algorithm != "" {
+		http.Error(w, "Regex to DFA conversion has no algorithm choice", http.StatusBadRequest)
+		return
+	}
+
+	dfa, derivatives, err := logic.RegexToDFA(req.Regex, req.Alphabet)
+	if err != nil {
+		http.Error(w, "Regex to DFA conversion error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(RegexToDFAResponse{FA: dfa, Derivatives: derivatives})
+}
+
+// DerivativeRequest represents request for the derivatives of a regex by a word
+type DerivativeRequest struct {
+	Regex    string   `json:"regex"`
+	Word     string   `json:"word"`
+	Alphabet []string `json:"alphabet,omitempty"` // symbols that classes and wildcards range over
+}
+
+// DerivativeHandler returns the derivative of a regex by every symbol of a word in turn
+func DerivativeHandler(w http.ResponseWriter, r *http.Request) {
+	var req DerivativeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if req.Regex == "" {
+		http.Error(w, "Missing regex field", http.StatusBadRequest)
+		return
+	}
+
+	steps, err := logic.Derivatives(req.Regex, req.Word, req.Alphabet)
+	if err != nil {
+		http.Error(w, "Derivative error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(steps)
+}
+
+// MinimizeResponse is a minimized FA along with its size after every step and,
+// when asked for, how it was obtained
+type MinimizeResponse struct {
+	*logic.FA
+	Stages []logic.MinimizationStage `json:"stages"`
+	Trace  *logic.MinimizationTrace  `json:"trace,omitempty"`
+}
+
+// MinimizeDFAHandler minimizes a DFA (or determinized NFA) with the algorithm
+// given by ?algorithm=hopcroft|moore|brzozowski, Hopcroft by default, with the
+// partition refinements and merged states if ?trace=true
 func MinimizeDFAHandler(w http.ResponseWriter, r *http.Request) {
 	uuid := r.URL.Query().Get("uuid")
 	if uuid == "" {
@@ -168,23 +346,40 @@ func MinimizeDFAHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	algorithm := logic.MinimizationAlgorithm(r.URL.Query().Get("algorithm"))
+	if algorithm == "" {
+		algorithm = logic.Hopcroft
+	}
+
 	dfa, err := loadFAFromAPI(uuid)
 	if err != nil {
 		http.Error(w, "Error loading DFA: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	minimized, err := logic.MinimizeDFA(dfa)
+	var response MinimizeResponse
+	if r.URL.Query().Get("trace") == "true" {
+		response.FA, response.Stages, response.Trace, err = logic.MinimizeDFATrace(dfa, algorithm)
+	} else {
+		response.FA, response.Stages, err = logic.MinimizeDFAWith(dfa, algorithm)
+	}
 	if err != nil {
 		http.Error(w, "DFA minimization error: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(minimized)
+	json.NewEncoder(w).Encode(response)
 }
 
-// ComplementHandler returns the complement of an FA
+// ComplementResponse is the complement of an FA along with the preprocessing
+// it required
+type ComplementResponse struct {
+	*logic.FA
+	Preprocessing logic.ComplementPreprocessing `json:"preprocessing"`
+}
+
+// ComplementHandler returns the complement of an FA, determinized and completed as needed
 func ComplementHandler(w http.ResponseWriter, r *http.Request) {
 	uuid := r.URL.Query().Get("uuid")
 	if uuid == "" {
@@ -198,10 +393,158 @@ func ComplementHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	complement := logic.Complement(fa)
+	complement, preprocessing, err := logic.Complement(fa)
+	if err != nil {
+		http.Error(w, "Complement error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ComplementResponse{FA: complement, Preprocessing: preprocessing})
+}
+
+// ReverseHandler returns the reversal of an FA
+func ReverseHandler(w http.ResponseWriter, r *http.Request) {
+	uuid := r.URL.Query().Get("uuid")
+	if uuid == "" {
+		http.Error(w, "Missing uuid parameter", http.StatusBadRequest)
+		return
+	}
+
+	fa, err := loadFAFromAPI(uuid)
+	if err != nil {
+		http.Error(w, "Error loading FA: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	reversed, err := logic.Reverse(fa)
+	if err != nil {
+		http.Error(w, "Reversal error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(reversed)
+}
+
+// StarHandler returns the Kleene star of an FA
+func StarHandler(w http.ResponseWriter, r *http.Request) {
+	uuid := r.URL.Query().Get("uuid")
+	if uuid == "" {
+		http.Error(w, "Missing uuid parameter", http.StatusBadRequest)
+		return
+	}
+
+	fa, err := loadFAFromAPI(uuid)
+	if err != nil {
+		http.Error(w, "Error loading FA: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	star, err := logic.Star(fa)
+	if err != nil {
+		http.Error(w, "Kleene star error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(star)
+}
+
+// PlusHandler returns the Kleene plus of an FA
+func PlusHandler(w http.ResponseWriter, r *http.Request) {
+	uuid := r.URL.Query().Get("uuid")
+	if uuid == "" {
+		http.Error(w, "Missing uuid parameter", http.StatusBadRequest)
+		return
+	}
+
+	fa, err := loadFAFromAPI(uuid)
+	if err != nil {
+		http.Error(w, "Error loading FA: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	plus, err := logic.Plus(fa)
+	if err != nil {
+		http.Error(w, "Kleene plus error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(plus)
+}
+
+// CompleteHandler adds a sink state so that every state of an FA has a transition on every symbol
+func CompleteHandler(w http.ResponseWriter, r *http.Request) {
+	uuid := r.URL.Query().Get("uuid")
+	if uuid == "" {
+		http.Error(w, "Missing uuid parameter", http.StatusBadRequest)
+		return
+	}
+
+	fa, err := loadFAFromAPI(uuid)
+	if err != nil {
+		http.Error(w, "Error loading FA: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	completed, err := logic.Complete(fa)
+	if err != nil {
+		http.Error(w, "Completion error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(completed)
+}
+
+// TrimHandler removes the states of an FA that are unreachable or cannot reach acceptance
+func TrimHandler(w http.ResponseWriter, r *http.Request) {
+	uuid := r.URL.Query().Get("uuid")
+	if uuid == "" {
+		http.Error(w, "Missing uuid parameter", http.StatusBadRequest)
+		return
+	}
+
+	fa, err := loadFAFromAPI(uuid)
+	if err != nil {
+		http.Error(w, "Error loading FA: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	trimmed, err := logic.Trim(fa)
+	if err != nil {
+		http.Error(w, "Trimming error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(trimmed)
+}
+
+// RemoveEpsilonHandler returns an equivalent FA without epsilon transitions and with the same states
+func RemoveEpsilonHandler(w http.ResponseWriter, r *http.Request) {
+	uuid := r.URL.Query().Get("uuid")
+	if uuid == "" {
+		http.Error(w, "Missing uuid parameter", http.StatusBadRequest)
+		return
+	}
+
+	fa, err := loadFAFromAPI(uuid)
+	if err != nil {
+		http.Error(w, "Error loading FA: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	epsilonFree, err := logic.RemoveEpsilon(fa)
+	if err != nil {
+		http.Error(w, "Epsilon removal error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(complement)
+	json.NewEncoder(w).Encode(epsilonFree)
 }
 
 // ConcatenationRequest represents request for FA concatenation
@@ -234,14 +577,14 @@ func ConcatenationHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Perform concatenation
-	result, err := logic.Concatenation(automata)
+	result, provenance, err := logic.Concatenation(automata)
 	if err != nil {
 		http.Error(w, "Concatenation error: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(result)
+	json.NewEncoder(w).Encode(CombinationResponse{FA: result, Provenance: provenance})
 }
 
 // Helper function to load FA from PostgREST API
@@ -37,7 +37,11 @@ func RunStringHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	accepted, path := logic.RunString(fa, req.String)
+	accepted, path, err := logic.RunString(fa, req.String)
+	if err != nil {
+		http.Error(w, "Run string error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
 
 	resp := RunStringResponse{
 		Accepted: accepted,
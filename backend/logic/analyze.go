@@ -0,0 +1,127 @@
+package logic
+
+import "math/big"
+
+// LanguageAnalysis summarizes the language accepted by an FA. Lengths count
+// symbols; LongestLength and Count are only set for finite languages and
+// ShortestLength only for nonempty ones.
+type LanguageAnalysis struct {
+	Empty          bool     `json:"empty"`
+	Finite         bool     `json:"finite"`
+	Universal      bool     `json:"universal"`
+	ShortestLength *int     `json:"shortest_length,omitempty"`
+	LongestLength  *int     `json:"longest_length,omitempty"`
+	Count          *big.Int `json:"count,omitempty"`
+}
+
+// Analyze reports emptiness, finiteness and universality of the language of
+// fa, together with the length of its shortest and longest words and the
+// number of words it contains when finite. The analysis runs on the
+// determinized FA, where distinct paths spell distinct words.
+func Analyze(fa *FA) (*LanguageAnalysis, error) {
+	a, err := compile(fa)
+	if err != nil {
+		return nil, err
+	}
+	dfa, _ := determinize(a)
+
+	analysis := &LanguageAnalysis{Universal: dfa.universal()}
+
+	word, found := dfa.shortestWord()
+	if !found {
+		analysis.Empty = true
+		analysis.Finite = true
+		analysis.Count = big.NewInt(0)
+		return analysis, nil
+	}
+	shortest := len(word)
+	analysis.ShortestLength = &shortest
+
+	order, acyclic := dfa.usefulOrder()
+	if !acyclic {
+		return analysis, nil
+	}
+	analysis.Finite = true
+
+	// Longest path and number of paths from the initial state to acceptance,
+	// computed backwards over the topological order of useful states
+	longest := make([]int, len(dfa.states))
+	count := make([]*big.Int, len(dfa.states))
+	for i := len(order) - 1; i >= 0; i-- {
+		q := order[i]
+		longest[q] = -1
+		count[q] = new(big.Int)
+		if dfa.accept[q] {
+			longest[q] = 0
+			count[q].SetInt64(1)
+		}
+		for _, p := range dfa.successors(q) {
+			if count[p] == nil {
+				continue // not useful
+			}
+			longest[q] = max(longest[q], longest[p]+1)
+			count[q].Add(count[q], count[p])
+		}
+	}
+	analysis.LongestLength = &longest[dfa.initial]
+	analysis.Count = count[dfa.initial]
+
+	return analysis, nil
+}
+
+// universal reports whether a deterministic, complete automaton accepts every
+// string over its alphabet, i.e. every reachable state is accepting.
+func (a *automaton) universal() bool {
+	for q, reached := range a.reachable() {
+		if reached && !a.accept[q] {
+			return false
+		}
+	}
+	return true
+}
+
+// usefulOrder returns the useful states, those both reachable and
+// coreachable, in topological order, and whether they form an acyclic graph.
+// A cycle among useful states means the language is infinite.
+func (a *automaton) usefulOrder() ([]int, bool) {
+	useful := a.useful()
+
+	const (
+		unvisited = iota
+		active
+		finished
+	)
+	color := make([]int, len(a.states))
+	var postorder []int
+	acyclic := true
+
+	var visit func(q int)
+	visit = func(q int) {
+		color[q] = active
+		for _, p := range a.successors(q) {
+			if !useful[p] {
+				continue
+			}
+			switch color[p] {
+			case active:
+				acyclic = false
+			case unvisited:
+				visit(p)
+			}
+		}
+		color[q] = finished
+		postorder = append(postorder, q)
+	}
+	for q := range a.states {
+		if useful[q] && color[q] == unvisited {
+			visit(q)
+		}
+	}
+
+	// Reverse postorder is a topological order
+	order := make([]int, len(postorder))
+	for i, q := range postorder {
+		order[len(postorder)-1-i] = q
+	}
+	return order, acyclic
+}
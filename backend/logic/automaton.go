@@ -0,0 +1,504 @@
+package logic
+
+import (
+	"fmt"
+	"math/bits"
+	"sort"
+	"strings"
+)
+
+// Reserved names used in the JSON representation of an FA.
+const (
+	epsilonSymbol = "@e" // alphabet column holding epsilon transitions
+	noState       = "@v" // empty transition cell
+	trapState     = "@t" // non-accepting sink added by determinization
+)
+
+// automaton is the compiled, index-based form of an FA that the algorithms in
+// this package operate on. States and symbols are identified by their position
+// and epsilon transitions are kept apart from the input alphabet.
+type automaton struct {
+	alphabet []string // input symbols, epsilon excluded
+	symbols  map[string]int
+	states   []string
+	initial  int
+	accept   []bool
+	delta    [][][]int // delta[q][a] holds the sorted targets of q on alphabet[a]
+	eps      [][]int   // eps[q] holds the sorted epsilon targets of q
+}
+
+// newAutomaton returns an automaton without states over the given alphabet.
+func newAutomaton(alphabet []string) *automaton {
+	a := &automaton{
+		alphabet: append([]string{}, alphabet...),
+		symbols:  make(map[string]int, len(alphabet)),
+	}
+	for i, symbol := range a.alphabet {
+		a.symbols[symbol] = i
+	}
+	return a
+}
+
+// compile converts the JSON representation of an FA into an automaton,
+// validating every state reference along the way.
+func compile(fa *FA) (*automaton, error) {
+	alphabet := make([]string, 0, len(fa.Alphabet))
+	columns := make([]int, len(fa.Alphabet)) // FA column -> symbol index, -1 for epsilon
+	seen := make(map[string]bool, len(fa.Alphabet))
+	for i, symbol := range fa.Alphabet {
+		if seen[symbol] {
+			return nil, fmt.Errorf("duplicate symbol %q in alphabet", symbol)
+		}
+		seen[symbol] = true
+		if symbol == epsilonSymbol {
+			columns[i] = -1
+			continue
+		}
+		columns[i] = len(alphabet)
+		alphabet = append(alphabet, symbol)
+	}
+
+	a := newAutomaton(alphabet)
+	index := make(map[string]int, len(fa.States))
+	for _, state := range fa.States {
+		if _, exists := index[state]; exists {
+			return nil, fmt.Errorf("duplicate state %q", state)
+		}
+		index[state] = a.addState(state, false)
+	}
+
+	initial, ok := index[fa.Initial]
+	if !ok {
+		return nil, fmt.Errorf("initial state %q is not a state", fa.Initial)
+	}
+	a.initial = initial
+
+	for _, state := range fa.Acceptance {
+		q, ok := index[state]
+		if !ok {
+			return nil, fmt.Errorf("acceptance state %q is not a state", state)
+		}
+		a.accept[q] = true
+	}
+
+	for q, row := range fa.Transitions {
+		if q >= len(fa.States) {
+			break
+		}
+		for i, cell := range row {
+			if i >= len(columns) {
+				break
+			}
+			for _, target := range cellTargets(cell) {
+				p, ok := index[target]
+				if !ok {
+					return nil, fmt.Errorf("transition from %q on %q to unknown state %q",
+						fa.States[q], fa.Alphabet[i], target)
+				}
+				if columns[i] < 0 {
+					a.addEpsilon(q, p)
+				} else {
+					a.addTransition(q, columns[i], p)
+				}
+			}
+		}
+	}
+
+	return a, nil
+}
+
+// cellTargets lists the state names held in a transition cell.
+func cellTargets(cell any) []string {
+	var targets []string
+	switch v := cell.(type) {
+	case string:
+		targets = []string{v}
+	case []string:
+		targets = v
+	case []any:
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				targets = append(targets, s)
+			}
+		}
+	}
+
+	result := make([]string, 0, len(targets))
+	for _, target := range targets {
+		if target != "" && target != noState {
+			result = append(result, target)
+		}
+	}
+	return result
+}
+
+// toFA converts the automaton back into its JSON representation. An epsilon
+// column is appended to the alphabet only when epsilon transitions exist.
+func (a *automaton) toFA() *FA {
+	alphabet := append([]string{}, a.alphabet...)
+	hasEpsilon := a.hasEpsilon()
+	if hasEpsilon {
+		alphabet = append(alphabet, epsilonSymbol)
+	}
+
+	transitions := make([][]any, len(a.states))
+	for q := range a.states {
+		row := make([]any, len(alphabet))
+		for i := range a.alphabet {
+			row[i] = a.cell(a.delta[q][i])
+		}
+		if hasEpsilon {
+			row[len(a.alphabet)] = a.cell(a.eps[q])
+		}
+		transitions[q] = row
+	}
+
+	acceptance := []string{}
+	for q, name := range a.states {
+		if a.accept[q] {
+			acceptance = append(acceptance, name)
+		}
+	}
+
+	initial := ""
+	if len(a.states) > 0 {
+		initial = a.states[a.initial]
+	}
+
+	return &FA{
+		Alphabet:    alphabet,
+		States:      append([]string{}, a.states...),
+		Initial:     initial,
+		Acceptance:  acceptance,
+		Transitions: transitions,
+	}
+}
+
+// cell encodes a target list the way FA transition cells expect it.
+func (a *automaton) cell(targets []int) any {
+	switch len(targets) {
+	case 0:
+		return noState
+	case 1:
+		return a.states[targets[0]]
+	default:
+		names := make([]string, len(targets))
+		for i, p := range targets {
+			names[i] = a.states[p]
+		}
+		return names
+	}
+}
+
+// clone returns a deep copy of the automaton.
+func (a *automaton) clone() *automaton {
+	return a.withAlphabet(a.alphabet)
+}
+
+// addState appends a state without transitions and returns its index.
+func (a *automaton) addState(name string, accepting bool) int {
+	a.states = append(a.states, name)
+	a.accept = append(a.accept, accepting)
+	a.delta = append(a.delta, make([][]int, len(a.alphabet)))
+	a.eps = append(a.eps, nil)
+	return len(a.states) - 1
+}
+
+// addTransition adds q -a-> p, ignoring duplicates.
+func (a *automaton) addTransition(q, symbol, p int) {
+	a.delta[q][symbol] = insertSorted(a.delta[q][symbol], p)
+}
+
+// addEpsilon adds q -ε-> p, ignoring duplicates.
+func (a *automaton) addEpsilon(q, p int) {
+	a.eps[q] = insertSorted(a.eps[q], p)
+}
+
+// insertSorted inserts v into the sorted slice s unless already present.
+func insertSorted(s []int, v int) []int {
+	i := sort.SearchInts(s, v)
+	if i < len(s) && s[i] == v {
+		return s
+	}
+	s = append(s, 0)
+	copy(s[i+1:], s[i:])
+	s[i] = v
+	return s
+}
+
+// hasEpsilon reports whether any state has an epsilon transition.
+func (a *automaton) hasEpsilon() bool {
+	for _, targets := range a.eps {
+		if len(targets) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// isDeterministic reports whether the automaton has no epsilon transitions and
+// at most one target per state and symbol.
+func (a *automaton) isDeterministic() bool {
+	if a.hasEpsilon() {
+		return false
+	}
+	for _, row := range a.delta {
+		for _, targets := range row {
+			if len(targets) > 1 {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// isComplete reports whether every state has a transition on every symbol.
+func (a *automaton) isComplete() bool {
+	for _, row := range a.delta {
+		for _, targets := range row {
+			if len(targets) == 0 {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// complete adds a non-accepting sink named @t (primed if taken) receiving every
+// missing transition, and reports whether one was needed.
+func (a *automaton) complete() bool {
+	if a.isComplete() {
+		return false
+	}
+	sink := a.addState(a.freshName(trapState), false)
+	for q := range a.states {
+		for symbol := range a.alphabet {
+			if len(a.delta[q][symbol]) == 0 {
+				a.addTransition(q, symbol, sink)
+			}
+		}
+	}
+	return true
+}
+
+// next returns the unique target of q on symbol, or -1 if there is none.
+// It must only be used on deterministic automata.
+func (a *automaton) next(q, symbol int) int {
+	if targets := a.delta[q][symbol]; len(targets) > 0 {
+		return targets[0]
+	}
+	return -1
+}
+
+// closure extends set with every state reachable through epsilon transitions.
+func (a *automaton) closure(set stateSet) {
+	stack := set.members()
+	for len(stack) > 0 {
+		q := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		for _, p := range a.eps[q] {
+			if set.add(p) {
+				stack = append(stack, p)
+			}
+		}
+	}
+}
+
+// move returns the states reachable from set on symbol, without closure.
+func (a *automaton) move(set stateSet, symbol int) stateSet {
+	result := newStateSet(len(a.states))
+	for _, q := range set.members() {
+		for _, p := range a.delta[q][symbol] {
+			result.add(p)
+		}
+	}
+	return result
+}
+
+// accepts reports whether set contains an accepting state.
+func (a *automaton) accepts(set stateSet) bool {
+	for _, q := range set.members() {
+		if a.accept[q] {
+			return true
+		}
+	}
+	return false
+}
+
+// names returns the state names of the members of set joined by sep.
+func (a *automaton) names(set stateSet, sep string) string {
+	members := set.members()
+	names := make([]string, len(members))
+	for i, q := range members {
+		names[i] = a.states[q]
+	}
+	return strings.Join(names, sep)
+}
+
+// reachable returns, for every state, whether it can be reached from the
+// initial state through any transition.
+func (a *automaton) reachable() []bool {
+	seen := make([]bool, len(a.states))
+	if len(a.states) == 0 {
+		return seen
+	}
+	seen[a.initial] = true
+	queue := []int{a.initial}
+	for len(queue) > 0 {
+		q := queue[0]
+		queue = queue[1:]
+		for _, p := range a.successors(q) {
+			if !seen[p] {
+				seen[p] = true
+				queue = append(queue, p)
+			}
+		}
+	}
+	return seen
+}
+
+// coreachable returns, for every state, whether an accepting state can be
+// reached from it through any transition.
+func (a *automaton) coreachable() []bool {
+	predecessors := make([][]int, len(a.states))
+	for q := range a.states {
+		for _, p := range a.successors(q) {
+			predecessors[p] = append(predecessors[p], q)
+		}
+	}
+
+	seen := make([]bool, len(a.states))
+	var queue []int
+	for q := range a.states {
+		if a.accept[q] {
+			seen[q] = true
+			queue = append(queue, q)
+		}
+	}
+	for len(queue) > 0 {
+		p := queue[0]
+		queue = queue[1:]
+		for _, q := range predecessors[p] {
+			if !seen[q] {
+				seen[q] = true
+				queue = append(queue, q)
+			}
+		}
+	}
+	return seen
+}
+
+// useful returns, for every state, whether it is both reachable and
+// coreachable, i.e. lies on a path from the initial state to acceptance.
+func (a *automaton) useful() []bool {
+	reachable, coreachable := a.reachable(), a.coreachable()
+	useful := make([]bool, len(a.states))
+	for q := range a.states {
+		useful[q] = reachable[q] && coreachable[q]
+	}
+	return useful
+}
+
+// trim returns a copy of the automaton holding only its useful states. The
+// initial state is always kept, so an empty language leaves it on its own.
+func (a *automaton) trim() *automaton {
+	keep := a.useful()
+	keep[a.initial] = true
+	return a.restrict(keep)
+}
+
+// successors lists the targets of every transition leaving q, epsilon included.
+func (a *automaton) successors(q int) []int {
+	var result []int
+	for _, targets := range a.delta[q] {
+		result = append(result, targets...)
+	}
+	return append(result, a.eps[q]...)
+}
+
+// restrict returns a copy of the automaton holding only the states marked in
+// keep. The initial state must be kept.
+func (a *automaton) restrict(keep []bool) *automaton {
+	result := newAutomaton(a.alphabet)
+	index := make([]int, len(a.states))
+	for q, name := range a.states {
+		index[q] = -1
+		if keep[q] {
+			index[q] = result.addState(name, a.accept[q])
+		}
+	}
+	result.initial = index[a.initial]
+	for q := range a.states {
+		if index[q] < 0 {
+			continue
+		}
+		for symbol, targets := range a.delta[q] {
+			for _, p := range targets {
+				if index[p] >= 0 {
+					result.addTransition(index[q], symbol, index[p])
+				}
+			}
+		}
+		for _, p := range a.eps[q] {
+			if index[p] >= 0 {
+				result.addEpsilon(index[q], index[p])
+			}
+		}
+	}
+	return result
+}
+
+// stateSet is a bitset over state indices.
+type stateSet []uint64
+
+func newStateSet(n int) stateSet {
+	return make(stateSet, (n+63)/64)
+}
+
+// add inserts q and reports whether it was not already present.
+func (s stateSet) add(q int) bool {
+	word, bit := q/64, uint64(1)<<(q%64)
+	if s[word]&bit != 0 {
+		return false
+	}
+	s[word] |= bit
+	return true
+}
+
+func (s stateSet) has(q int) bool {
+	return s[q/64]&(uint64(1)<<(q%64)) != 0
+}
+
+func (s stateSet) empty() bool {
+	for _, word := range s {
+		if word != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// members lists the states in the set in increasing order.
+func (s stateSet) members() []int {
+	var result []int
+	for i, word := range s {
+		for word != 0 {
+			bit := bits.TrailingZeros64(word)
+			result = append(result, i*64+bit)
+			word &= word - 1
+		}
+	}
+	return result
+}
+
+// key encodes the set as a string usable as a map key.
+func (s stateSet) key() string {
+	var b strings.Builder
+	b.Grow(len(s) * 8)
+	for _, word := range s {
+		for i := 0; i < 8; i++ {
+			b.WriteByte(byte(word >> (8 * i)))
+		}
+	}
+	return b.String()
+}
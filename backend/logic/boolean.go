@@ -0,0 +1,239 @@
+package logic
+
+import (
+	"fmt"
+	"unicode"
+)
+
+// acceptance returns the condition a product tuple must meet to be accepting,
+// given the acceptance of each of its components.
+func (mode BooleanMode) acceptance() (func([]bool) bool, error) {
+	switch mode {
+	case Union:
+		// Accept if ANY component state is accepting
+		return func(flags []bool) bool {
+			for _, flag := range flags {
+				if flag {
+					return true
+				}
+			}
+			return false
+		}, nil
+	case Intersection:
+		// Accept if ALL component states are accepting
+		return func(flags []bool) bool {
+			for _, flag := range flags {
+				if !flag {
+					return false
+				}
+			}
+			return true
+		}, nil
+	case Difference:
+		// Accept if the first component accepts and no other does
+		return func(flags []bool) bool {
+			if !flags[0] {
+				return false
+			}
+			for _, flag := range flags[1:] {
+				if flag {
+					return false
+				}
+			}
+			return true
+		}, nil
+	case SymmetricDifference:
+		// Accept if an odd number of components accept
+		return func(flags []bool) bool {
+			odd := false
+			for _, flag := range flags {
+				odd = odd != flag
+			}
+			return odd
+		}, nil
+	default:
+		return nil, fmt.Errorf("unsupported boolean mode %q", mode)
+	}
+}
+
+// PerformBooleanExpression builds the deterministic product of fas accepting
+// the language described by expression. Operands are the letters A, B, C, ...
+// referring to fas in order, combined with union (∪ | +), difference (\ -) and
+// symmetric difference (⊕ ^), which associate left with the lowest precedence,
+// then intersection (∩ &) and complement (¬ ! ~), e.g. "A ∩ ¬B ∪ C".
+// Complement is taken relative to the shared alphabet.
+func PerformBooleanExpression(fas []*FA, expression string) (*FA, error) {
+	parser := &booleanParser{runes: []rune(expression)}
+	expr, err := parser.parse()
+	if err != nil {
+		return nil, err
+	}
+
+	// Only the FAs named in the expression take part in the product
+	used := make([]bool, len(fas))
+	for _, operand := range expr.operands(nil) {
+		if operand >= len(fas) {
+			return nil, fmt.Errorf("operand %c refers to FA %d but only %d were given",
+				'A'+operand, operand+1, len(fas))
+		}
+		used[operand] = true
+	}
+	position := make([]int, len(fas))
+	var operands []*FA
+	for i, fa := range fas {
+		if used[i] {
+			position[i] = len(operands)
+			operands = append(operands, fa)
+		}
+	}
+
+	return booleanProduct(operands, func(flags []bool) bool {
+		return expr.eval(func(operand int) bool {
+			return flags[position[operand]]
+		})
+	})
+}
+
+// boolExpr is a node of a parsed boolean expression over FA operands.
+type boolExpr struct {
+	op          rune // 0 for an operand, otherwise one of ∪ ∩ \ ⊕ ¬
+	operand     int
+	left, right *boolExpr
+}
+
+// eval evaluates the expression given the truth value of each operand.
+func (e *boolExpr) eval(value func(int) bool) bool {
+	switch e.op {
+	case '∪':
+		return e.left.eval(value) || e.right.eval(value)
+	case '∩':
+		return e.left.eval(value) && e.right.eval(value)
+	case '\\':
+		return e.left.eval(value) && !e.right.eval(value)
+	case '⊕':
+		return e.left.eval(value) != e.right.eval(value)
+	case '¬':
+		return !e.left.eval(value)
+	default:
+		return value(e.operand)
+	}
+}
+
+// operands appends the operand indices referenced by the expression to dst.
+func (e *boolExpr) operands(dst []int) []int {
+	if e.op == 0 {
+		return append(dst, e.operand)
+	}
+	dst = e.left.operands(dst)
+	if e.right != nil {
+		dst = e.right.operands(dst)
+	}
+	return dst
+}
+
+// booleanParser implements a recursive descent parser for boolean expressions
+type booleanParser struct {
+	runes []rune
+	pos   int
+}
+
+// Canonical operator for every accepted spelling
+var booleanOperators = map[rune]rune{
+	'∪': '∪', '|': '∪', '+': '∪',
+	'∩': '∩', '&': '∩',
+	'\\': '\\', '-': '\\',
+	'⊕': '⊕', '^': '⊕',
+	'¬': '¬', '!': '¬', '~': '¬',
+}
+
+func (p *booleanParser) parse() (*boolExpr, error) {
+	expr, err := p.parseUnion()
+	if err != nil {
+		return nil, err
+	}
+	if p.peek() != 0 {
+		return nil, fmt.Errorf("unexpected character %q at position %d", p.runes[p.pos], p.pos)
+	}
+	return expr, nil
+}
+
+// Peek at the current operator or operand, skipping whitespace. Operators
+// are returned in canonical form, so that errors quote p.runes[p.pos] instead.
+func (p *booleanParser) peek() rune {
+	for p.pos < len(p.runes) && unicode.IsSpace(p.runes[p.pos]) {
+		p.pos++
+	}
+	if p.pos >= len(p.runes) {
+		return 0
+	}
+	if op, ok := booleanOperators[p.runes[p.pos]]; ok {
+		return op
+	}
+	return p.runes[p.pos]
+}
+
+// Parse union, difference and symmetric difference (lowest precedence, left associative)
+func (p *booleanParser) parseUnion() (*boolExpr, error) {
+	left, err := p.parseIntersection()
+	if err != nil {
+		return nil, err
+	}
+	for op := p.peek(); op == '∪' || op == '\\' || op == '⊕'; op = p.peek() {
+		p.pos++
+		right, err := p.parseIntersection()
+		if err != nil {
+			return nil, err
+		}
+		left = &boolExpr{op: op, left: left, right: right}
+	}
+	return left, nil
+}
+
+// Parse intersection
+func (p *booleanParser) parseIntersection() (*boolExpr, error) {
+	left, err := p.parseFactor()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek() == '∩' {
+		p.pos++
+		right, err := p.parseFactor()
+		if err != nil {
+			return nil, err
+		}
+		left = &boolExpr{op: '∩', left: left, right: right}
+	}
+	return left, nil
+}
+
+// Parse complement, parenthesized expressions and operands
+func (p *booleanParser) parseFactor() (*boolExpr, error) {
+	ch := p.peek()
+	switch {
+	case ch == 0:
+		return nil, fmt.Errorf("unexpected end of expression")
+	case ch == '¬':
+		p.pos++
+		operand, err := p.parseFactor()
+		if err != nil {
+			return nil, err
+		}
+		return &boolExpr{op: '¬', left: operand}, nil
+	case ch == '(':
+		p.pos++
+		expr, err := p.parseUnion()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek() != ')' {
+			return nil, fmt.Errorf("expected closing parenthesis at position %d", p.pos)
+		}
+		p.pos++
+		return expr, nil
+	case ch >= 'A' && ch <= 'Z':
+		p.pos++
+		return &boolExpr{operand: int(ch - 'A')}, nil
+	default:
+		return nil, fmt.Errorf("unexpected character %q at position %d", p.runes[p.pos], p.pos)
+	}
+}
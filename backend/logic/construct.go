@@ -0,0 +1,166 @@
+package logic
+
+import (
+	"fmt"
+	"slices"
+)
+
+// RegexAlgorithm selects how RegexToNFAWith builds an NFA.
+type RegexAlgorithm string
+
+const (
+	Thompson  RegexAlgorithm = "thompson"  // epsilon-linked fragments
+	Glushkov  RegexAlgorithm = "glushkov"  // position automaton
+	Antimirov RegexAlgorithm = "antimirov" // partial derivatives
+)
+
+// glushkov builds the position automaton of an expression: an initial state
+// q0 plus one state qi per occurrence of a symbol, numbered left to right. A
+// transition on a enters a position labeled a, from q0 if the position can
+// come first and from position i if it can follow i. The automaton is free of
+// epsilon transitions and has exactly one state more than there are symbol
+// occurrences. The automaton is over symbols, which must include those of the
+// expression.
+func glushkov(node *Regex, symbols []string) *automaton {
+	a := newAutomaton(symbols)
+	a.addState("q0", node.nullable())
+
+	// Number the positions and collect their follow sets
+	var positions []string
+	var follow [][]int
+	var visit func(n *Regex) (first, last []int)
+	visit = func(n *Regex) (first, last []int) {
+		switch n.Op {
+		case RegexSymbol:
+			positions = append(positions, n.Symbol)
+			follow = append(follow, nil)
+			p := len(positions)
+			return []int{p}, []int{p}
+		case RegexUnion:
+			leftFirst, leftLast := visit(n.Left)
+			rightFirst, rightLast := visit(n.Right)
+			return slices.Concat(leftFirst, rightFirst), slices.Concat(leftLast, rightLast)
+		case RegexConcat:
+			leftFirst, leftLast := visit(n.Left)
+			rightFirst, rightLast := visit(n.Right)
+			for _, p := range leftLast {
+				follow[p-1] = append(follow[p-1], rightFirst...)
+			}
+			first, last = leftFirst, rightLast
+			if n.Left.nullable() {
+				first = slices.Concat(first, rightFirst)
+			}
+			if n.Right.nullable() {
+				last = slices.Concat(last, leftLast)
+			}
+			return first, last
+		case RegexStar, RegexPlus:
+			first, last = visit(n.Left)
+			for _, p := range last {
+				follow[p-1] = append(follow[p-1], first...)
+			}
+			return first, last
+		default:
+			return nil, nil
+		}
+	}
+	first, last := visit(node)
+
+	for p := range positions {
+		a.addState(fmt.Sprintf("q%d", p+1), false)
+	}
+	for _, p := range last {
+		a.accept[p] = true
+	}
+	for _, p := range first {
+		a.addTransition(0, a.symbols[positions[p-1]], p)
+	}
+	for q, targets := range follow {
+		for _, p := range targets {
+			a.addTransition(q+1, a.symbols[positions[p-1]], p)
+		}
+	}
+	a.initial = 0
+	return a
+}
+
+// antimirov builds the partial derivative automaton of an expression. Its
+// states are the expression itself and its partial derivatives by every word,
+// named q0..qn in discovery order; a state accepts if its expression is
+// nullable, and leads on a to each of its partial derivatives by a. There are
+// at most as many states as in the position automaton. The automaton is over
+// symbols, which must include those of the expression.
+func antimirov(node *Regex, symbols []string) *automaton {
+	a := newAutomaton(symbols)
+	index := make(map[string]int)
+	var terms []*Regex
+
+	visit := func(term *Regex) int {
+		key := term.key()
+		if q, ok := index[key]; ok {
+			return q
+		}
+		q := a.addState(fmt.Sprintf("q%d", len(terms)), term.nullable())
+		index[key] = q
+		terms = append(terms, term)
+		return q
+	}
+
+	a.initial = visit(node)
+	for q := 0; q < len(terms); q++ {
+		for symbol, name := range a.alphabet {
+			for _, derivative := range terms[q].partialDerivatives(name) {
+				a.addTransition(q, symbol, visit(derivative))
+			}
+		}
+	}
+	return a
+}
+
+// partialDerivatives returns Antimirov's partial derivatives of the expression
+// by symbol: expressions whose union matches the words w such that symbol·w is
+// matched by the original one.
+func (n *Regex) partialDerivatives(symbol string) []*Regex {
+	switch n.Op {
+	case RegexSymbol:
+		if n.Symbol == symbol {
+			return []*Regex{{Op: RegexEpsilon}}
+		}
+		return nil
+	case RegexUnion:
+		return append(n.Left.partialDerivatives(symbol), n.Right.partialDerivatives(symbol)...)
+	case RegexConcat:
+		var result []*Regex
+		for _, derivative := range n.Left.partialDerivatives(symbol) {
+			result = append(result, concatNodes(derivative, n.Right))
+		}
+		if n.Left.nullable() {
+			result = append(result, n.Right.partialDerivatives(symbol)...)
+		}
+		return result
+	case RegexStar, RegexPlus:
+		star := n
+		if n.Op == RegexPlus {
+			star = &Regex{Op: RegexStar, Left: n.Left}
+		}
+		var result []*Regex
+		for _, derivative := range n.Left.partialDerivatives(symbol) {
+			result = append(result, concatNodes(derivative, star))
+		}
+		return result
+	default:
+		return nil
+	}
+}
+
+// concatNodes concatenates two expressions, dropping a leading ε and
+// associating to the right so that equal derivatives get equal keys.
+func concatNodes(left, right *Regex) *Regex {
+	switch left.Op {
+	case RegexEpsilon:
+		return right
+	case RegexConcat:
+		return concatNodes(left.Left, concatNodes(left.Right, right))
+	}
+	return &Regex{Op: RegexConcat, Left: left, Right: right}
+}
@@ -0,0 +1,208 @@
+package logic
+
+import (
+	"slices"
+	"strings"
+)
+
+// EquivalenceResult reports whether two FAs accept the same language. When
+// they do not, Counterexample is a shortest string accepted by exactly one of
+// them and AcceptedBy tells which (1 for the first FA, 2 for the second).
+type EquivalenceResult struct {
+	Equivalent     bool    `json:"equivalent"`
+	Counterexample *string `json:"counterexample,omitempty"`
+	AcceptedBy     int     `json:"accepted_by,omitempty"`
+}
+
+// Equivalent decides whether a and b accept the same language by searching
+// the product of their determinized forms for a reachable state accepted by
+// exactly one of them.
+func Equivalent(a, b *FA) (*EquivalenceResult, error) {
+	automata, err := deterministicPair(a, b)
+	if err != nil {
+		return nil, err
+	}
+
+	differ := product(automata, func(flags []bool) bool { return flags[0] != flags[1] })
+	word, found := differ.shortestWord()
+	if !found {
+		return &EquivalenceResult{Equivalent: true}, nil
+	}
+
+	counterexample := differ.spell(word)
+	acceptedBy := 2
+	if automata[0].run(word) {
+		acceptedBy = 1
+	}
+	return &EquivalenceResult{
+		Counterexample: &counterexample,
+		AcceptedBy:     acceptedBy,
+	}, nil
+}
+
+// InclusionResult reports whether every string accepted by the first FA is
+// also accepted by the second. When it is not, Witness is a shortest string
+// accepted by the first FA only.
+type InclusionResult struct {
+	Subset  bool    `json:"subset"`
+	Witness *string `json:"witness,omitempty"`
+}
+
+// Subset decides whether the language of a is included in that of b, i.e.
+// whether the difference a \ b is empty.
+func Subset(a, b *FA) (*InclusionResult, error) {
+	witness, err := pairWitness(a, b, Difference)
+	if err != nil {
+		return nil, err
+	}
+	return &InclusionResult{Subset: witness == nil, Witness: witness}, nil
+}
+
+// DisjointnessResult reports whether two FAs share no accepted string. When
+// they do, Witness is a shortest string accepted by both.
+type DisjointnessResult struct {
+	Disjoint bool    `json:"disjoint"`
+	Witness  *string `json:"witness,omitempty"`
+}
+
+// Disjoint decides whether the languages of a and b do not intersect, i.e.
+// whether the intersection a ∩ b is empty.
+func Disjoint(a, b *FA) (*DisjointnessResult, error) {
+	witness, err := pairWitness(a, b, Intersection)
+	if err != nil {
+		return nil, err
+	}
+	return &DisjointnessResult{Disjoint: witness == nil, Witness: witness}, nil
+}
+
+// pairWitness returns a shortest string in the language obtained by combining
+// a and b with mode, or nil when that language is empty.
+func pairWitness(a, b *FA, mode BooleanMode) (*string, error) {
+	automata, err := deterministicPair(a, b)
+	if err != nil {
+		return nil, err
+	}
+	accept, err := mode.acceptance()
+	if err != nil {
+		return nil, err
+	}
+
+	combined := product(automata, accept)
+	word, found := combined.shortestWord()
+	if !found {
+		return nil, nil
+	}
+	witness := combined.spell(word)
+	return &witness, nil
+}
+
+// deterministicPair compiles a pair of FAs into deterministic automata over a
+// shared alphabet, ready to be combined with product.
+func deterministicPair(a, b *FA) ([]*automaton, error) {
+	automata, err := compileAll([]*FA{a, b})
+	if err != nil {
+		return nil, err
+	}
+	automata = alignAlphabets(automata)
+	for i, x := range automata {
+		if !x.isDeterministic() {
+			automata[i], _ = determinize(x)
+		}
+	}
+	return automata, nil
+}
+
+// shortestWord returns a shortest sequence of symbols leading from the initial
+// state to an accepting one, if any. Epsilon transitions cost nothing, so the
+// search is a 0-1 breadth-first search pushing their targets to the front.
+func (a *automaton) shortestWord() ([]int, bool) {
+	if len(a.states) == 0 {
+		return nil, false
+	}
+
+	type step struct{ from, symbol int }
+	parent := make([]step, len(a.states))
+	dist := make([]int, len(a.states))
+	done := make([]bool, len(a.states))
+	for q := range dist {
+		dist[q] = -1
+	}
+	dist[a.initial] = 0
+	parent[a.initial] = step{-1, -1}
+
+	// The deque is a stack of zero-cost targets in front of a queue of the
+	// others, so that both ends are pushed to in constant time
+	var front, back []int
+	relax := func(q, p, symbol int) {
+		cost := 1
+		if symbol < 0 {
+			cost = 0
+		}
+		if dist[p] >= 0 && dist[p] <= dist[q]+cost {
+			return
+		}
+		dist[p] = dist[q] + cost
+		parent[p] = step{q, symbol}
+		if cost == 0 {
+			front = append(front, p)
+		} else {
+			back = append(back, p)
+		}
+	}
+
+	back = append(back, a.initial)
+	for head := 0; len(front) > 0 || head < len(back); {
+		var q int
+		if n := len(front); n > 0 {
+			q, front = front[n-1], front[:n-1]
+		} else {
+			q = back[head]
+			head++
+		}
+		if done[q] {
+			continue
+		}
+		done[q] = true
+
+		if a.accept[q] {
+			var word []int
+			for ; parent[q].from >= 0; q = parent[q].from {
+				if parent[q].symbol >= 0 {
+					word = append(word, parent[q].symbol)
+				}
+			}
+			slices.Reverse(word)
+			return word, true
+		}
+		for _, p := range a.eps[q] {
+			relax(q, p, -1)
+		}
+		for symbol, targets := range a.delta[q] {
+			for _, p := range targets {
+				relax(q, p, symbol)
+			}
+		}
+	}
+	return nil, false
+}
+
+// run reports whether the automaton accepts the given sequence of symbols.
+func (a *automaton) run(word []int) bool {
+	current := newStateSet(len(a.states))
+	current.add(a.initial)
+	a.closure(current)
+	for _, symbol := range word {
+		current = a.move(current, symbol)
+		a.closure(current)
+	}
+	return a.accepts(current)
+}
+
+// spell turns a sequence of symbols into the string they form.
+func (a *automaton) spell(word []int) string {
+	var b strings.Builder
+	for _, symbol := range word {
+		b.WriteString(a.alphabet[symbol])
+	}
+	return b.String()
+}
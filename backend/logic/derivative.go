@@ -0,0 +1,213 @@
+package logic
+
+import (
+	"fmt"
+	"sort"
+)
+
+// DerivativeStep is the Brzozowski derivative of a regular expression by a
+// prefix of a word, ending with Symbol.
+type DerivativeStep struct {
+	Symbol     string `json:"symbol"`
+	Derivative string `json:"derivative"`
+	Nullable   bool   `json:"nullable"` // the prefix is matched by the expression
+}
+
+// Derivatives takes the Brzozowski derivative of regex by every symbol of
+// word in turn, returning one step per symbol. The word is matched if the last
+// derivative is nullable. Classes and wildcards are expanded against alphabet.
+// An empty regex stands for the empty language.
+func Derivatives(regex, word string, alphabet []string) ([]DerivativeStep, error) {
+	node, err := parseConversion(regex, alphabet)
+	if err != nil {
+		return nil, err
+	}
+	node = node.normalize()
+
+	steps := []DerivativeStep{}
+	for _, symbol := range word {
+		node = node.derivative(string(symbol))
+		steps = append(steps, DerivativeStep{
+			Symbol:     string(symbol),
+			Derivative: node.String(),
+			Nullable:   node.nullable(),
+		})
+	}
+	return steps, nil
+}
+
+// RegexToDFA builds a DFA directly from a regular expression, without going
+// through an NFA. Its states are the distinct derivatives of the expression
+// by every word, named q0..qn in discovery order, with the empty derivative ∅
+// as the trap state @t last. Derivatives are kept in normal form by smart
+// constructors, which guarantees there are finitely many. The returned map
+// gives the derivative every state stands for. The DFA is over alphabet,
+// against which classes and wildcards are expanded, and the symbols of the
+// expression. An empty regex stands for the empty language.
+func RegexToDFA(regex string, alphabet []string) (*FA, map[string]string, error) {
+	node, err := parseConversion(regex, alphabet)
+	if err != nil {
+		return nil, nil, err
+	}
+	// The alphabet is taken before normalization, which may drop symbols
+	a := newAutomaton(node.alphabetOver(alphabet))
+	node = node.normalize()
+
+	index := make(map[string]int)
+	var terms []*Regex
+	visit := func(term *Regex) int {
+		key := term.key()
+		if q, ok := index[key]; ok {
+			return q
+		}
+		q := a.addState(fmt.Sprintf("q%d", len(terms)), term.nullable())
+		index[key] = q
+		terms = append(terms, term)
+		return q
+	}
+
+	// Transitions into ∅ are resolved once every derivative is known, so that
+	// the trap state comes last, unless ∅ is where the construction starts
+	a.initial = visit(node)
+	var toTrap [][2]int
+	for q := 0; q < len(terms); q++ {
+		for symbol, name := range a.alphabet {
+			derivative := terms[q].derivative(name)
+			if derivative.Op == RegexEmpty && node.Op != RegexEmpty {
+				toTrap = append(toTrap, [2]int{q, symbol})
+				continue
+			}
+			a.addTransition(q, symbol, visit(derivative))
+		}
+	}
+	if len(toTrap) > 0 {
+		trap := a.addState(trapState, false)
+		terms = append(terms, &Regex{Op: RegexEmpty})
+		for symbol := range a.alphabet {
+			a.addTransition(trap, symbol, trap)
+		}
+		for _, t := range toTrap {
+			a.addTransition(t[0], t[1], trap)
+		}
+	}
+
+	derivatives := make(map[string]string, len(terms))
+	for q, term := range terms {
+		derivatives[a.states[q]] = term.String()
+	}
+	return a.toFA(), derivatives, nil
+}
+
+// derivative returns the Brzozowski derivative of a normalized expression by
+// symbol, matching the words w such that symbol·w is matched by the original
+// expression. The result is normalized as well.
+func (n *Regex) derivative(symbol string) *Regex {
+	switch n.Op {
+	case RegexSymbol:
+		if n.Symbol == symbol {
+			return &Regex{Op: RegexEpsilon}
+		}
+		return &Regex{Op: RegexEmpty}
+	case RegexUnion:
+		return unionOf(n.Left.derivative(symbol), n.Right.derivative(symbol))
+	case RegexConcat:
+		derivative := concatOf(n.Left.derivative(symbol), n.Right)
+		if n.Left.nullable() {
+			return unionOf(derivative, n.Right.derivative(symbol))
+		}
+		return derivative
+	case RegexStar:
+		return concatOf(n.Left.derivative(symbol), n)
+	case RegexPlus:
+		return concatOf(n.Left.derivative(symbol), starOf(n.Left))
+	default:
+		return &Regex{Op: RegexEmpty}
+	}
+}
+
+// normalize rebuilds the expression bottom-up with the smart constructors.
+func (n *Regex) normalize() *Regex {
+	switch n.Op {
+	case RegexUnion:
+		return unionOf(n.Left.normalize(), n.Right.normalize())
+	case RegexConcat:
+		return concatOf(n.Left.normalize(), n.Right.normalize())
+	case RegexStar:
+		return starOf(n.Left.normalize())
+	case RegexPlus:
+		// ∅+ = ∅, ε+ = ε, (r*)+ = r* and (r+)+ = r+
+		left := n.Left.normalize()
+		switch left.Op {
+		case RegexEmpty, RegexEpsilon, RegexStar, RegexPlus:
+			return left
+		}
+		return &Regex{Op: RegexPlus, Left: left}
+	default:
+		return n
+	}
+}
+
+// unionOf builds left ∪ right in normal form: nested unions are flattened,
+// ∅ is dropped, duplicates are removed and the alternatives are sorted, so
+// that unions equal up to associativity, commutativity and idempotence are
+// built identically.
+func unionOf(left, right *Regex) *Regex {
+	alternatives := make(map[string]*Regex)
+	var collect func(*Regex)
+	collect = func(n *Regex) {
+		switch n.Op {
+		case RegexUnion:
+			collect(n.Left)
+			collect(n.Right)
+		case RegexEmpty:
+		default:
+			alternatives[n.key()] = n
+		}
+	}
+	collect(left)
+	collect(right)
+
+	keys := make([]string, 0, len(alternatives))
+	for key := range alternatives {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	if len(keys) == 0 {
+		return &Regex{Op: RegexEmpty}
+	}
+	result := alternatives[keys[len(keys)-1]]
+	for i := len(keys) - 2; i >= 0; i-- {
+		result = &Regex{Op: RegexUnion, Left: alternatives[keys[i]], Right: result}
+	}
+	return result
+}
+
+// concatOf builds left·right in normal form: ∅ absorbs, ε is the identity
+// and concatenations associate to the right.
+func concatOf(left, right *Regex) *Regex {
+	switch {
+	case left.Op == RegexEmpty || right.Op == RegexEmpty:
+		return &Regex{Op: RegexEmpty}
+	case left.Op == RegexEpsilon:
+		return right
+	case right.Op == RegexEpsilon:
+		return left
+	case left.Op == RegexConcat:
+		return concatOf(left.Left, concatOf(left.Right, right))
+	}
+	return &Regex{Op: RegexConcat, Left: left, Right: right}
+}
+
+// starOf builds left* in normal form: ∅* = ε* = ε and (r*)* = (r+)* = r*.
+func starOf(left *Regex) *Regex {
+	switch left.Op {
+	case RegexEmpty, RegexEpsilon:
+		return &Regex{Op: RegexEpsilon}
+	case RegexStar:
+		return left
+	case RegexPlus:
+		return &Regex{Op: RegexStar, Left: left.Left}
+	}
+	return &Regex{Op: RegexStar, Left: left}
+}
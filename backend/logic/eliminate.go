@@ -0,0 +1,216 @@
+package logic
+
+import (
+	"fmt"
+	"slices"
+)
+
+// EliminationOrder selects the order in which RegexFromFAWith eliminates the
+// states of an FA. The order does not change the language of the result, but
+// it can change its size considerably.
+type EliminationOrder string
+
+const (
+	ArrayOrder  EliminationOrder = "array"  // order of the states in the FA
+	FewestEdges EliminationOrder = "edges"  // fewest incoming × outgoing edges first
+	LabelWeight EliminationOrder = "weight" // smallest growth in label size first
+	CustomOrder EliminationOrder = "custom" // states named by the user first
+)
+
+// EliminationSize is the simplified regex an elimination order produces and
+// its size, as counted by Regex.Size.
+type EliminationSize struct {
+	Order EliminationOrder `json:"order"`
+	Regex string           `json:"regex"`
+	Size  int              `json:"size"`
+}
+
+// EliminationStep is the generalized NFA left after eliminating a state, or
+// before eliminating any if Eliminated is empty. Its edges are labeled with
+// regexes, which make up its alphabet; S and F are the added start and final
+// states (primed if the FA already uses those names).
+type EliminationStep struct {
+	Eliminated string `json:"eliminated,omitempty"`
+	GNFA       *FA    `json:"gnfa"`
+}
+
+// CompareEliminationOrders converts an FA to a simplified regex with every
+// elimination order, including the custom one if states are given.
+func CompareEliminationOrders(fa *FA, custom []string) ([]EliminationSize, error) {
+	orders := []EliminationOrder{ArrayOrder, FewestEdges, LabelWeight}
+	if len(custom) > 0 {
+		orders = append(orders, CustomOrder)
+	}
+
+	var sizes []EliminationSize
+	for _, order := range orders {
+		regex, err := RegexFromFAWith(fa, order, custom)
+		if err != nil {
+			return nil, err
+		}
+		regex = regex.Simplify()
+		sizes = append(sizes, EliminationSize{Order: order, Regex: regex.String(), Size: regex.Size()})
+	}
+	return sizes, nil
+}
+
+// Size returns the number of symbol occurrences in the expression, also known
+// as its alphabetic width.
+func (r *Regex) Size() int {
+	switch r.Op {
+	case RegexSymbol:
+		return 1
+	case RegexUnion, RegexConcat:
+		return r.Left.Size() + r.Right.Size()
+	case RegexStar, RegexPlus:
+		return r.Left.Size()
+	default:
+		return 0
+	}
+}
+
+// eliminationOrder returns a function choosing the next state to eliminate
+// from the state elimination matrix, where state q sits at index q+1 between
+// the added start and end states, given which indices are already removed.
+func (a *automaton) eliminationOrder(order EliminationOrder, custom []string) (func([][]*Regex, []bool) int, error) {
+	switch order {
+	case ArrayOrder:
+		return func(matrix [][]*Regex, removed []bool) int {
+			return lowestScore(removed, func(int) int { return 0 })
+		}, nil
+	case FewestEdges:
+		return func(matrix [][]*Regex, removed []bool) int {
+			return lowestScore(removed, func(k int) int {
+				in, out := edgesAround(matrix, removed, k)
+				return len(in) * len(out)
+			})
+		}, nil
+	case LabelWeight:
+		// The weight of a state is how much eliminating it increases the
+		// total size of the labels: every incoming label is copied once per
+		// outgoing edge and vice versa, the loop once per new edge
+		return func(matrix [][]*Regex, removed []bool) int {
+			return lowestScore(removed, func(k int) int {
+				in, out := edgesAround(matrix, removed, k)
+				weight := matrix[k][k].Size() * (len(in)*len(out) - 1)
+				for _, i := range in {
+					weight += matrix[i][k].Size() * (len(out) - 1)
+				}
+				for _, j := range out {
+					weight += matrix[k][j].Size() * (len(in) - 1)
+				}
+				return weight
+			})
+		}, nil
+	case CustomOrder:
+		var indices []int
+		seen := make(map[string]bool)
+		for _, name := range custom {
+			q := slices.Index(a.states, name)
+			if q < 0 {
+				return nil, fmt.Errorf("unknown state %q in elimination order", name)
+			}
+			if seen[name] {
+				return nil, fmt.Errorf("state %q appears twice in elimination order", name)
+			}
+			seen[name] = true
+			indices = append(indices, q+1)
+		}
+		return func(matrix [][]*Regex, removed []bool) int {
+			for _, k := range indices {
+				if !removed[k] {
+					return k
+				}
+			}
+			return lowestScore(removed, func(int) int { return 0 })
+		}, nil
+	default:
+		return nil, fmt.Errorf("unsupported elimination order %q", order)
+	}
+}
+
+// lowestScore returns the remaining state with the lowest score, the first
+// one on ties. The start and end states are never candidates.
+func lowestScore(removed []bool, score func(int) int) int {
+	best, bestScore := -1, 0
+	for k := 1; k < len(removed)-1; k++ {
+		if removed[k] {
+			continue
+		}
+		if s := score(k); best < 0 || s < bestScore {
+			best, bestScore = k, s
+		}
+	}
+	return best
+}
+
+// edgesAround returns the remaining states with an edge into k and those with
+// an edge out of k, self-loops excluded.
+func edgesAround(matrix [][]*Regex, removed []bool, k int) (in, out []int) {
+	for i := range matrix {
+		if removed[i] || i == k {
+			continue
+		}
+		if matrix[i][k].Op != RegexEmpty {
+			in = append(in, i)
+		}
+		if matrix[k][i].Op != RegexEmpty {
+			out = append(out, i)
+		}
+	}
+	return in, out
+}
+
+// gnfa renders the remaining states of a state elimination matrix as an FA
+// whose alphabet is the set of distinct edge labels, in order of appearance.
+// Labels are told apart by key; one printing like an earlier, different label
+// is shown in its fully parenthesized key form instead, primed if need be.
+func (a *automaton) gnfa(matrix [][]*Regex, removed []bool) *FA {
+	n := len(matrix)
+	names := make([]string, n)
+	names[0], names[n-1] = a.freshName("S"), a.freshName("F")
+	copy(names[1:], a.states)
+
+	var labels []string
+	symbols := make(map[string]int) // label key to symbol
+	shown := make(map[string]bool)
+	for i := range matrix {
+		for j := range matrix {
+			if removed[i] || removed[j] || matrix[i][j].Op == RegexEmpty {
+				continue
+			}
+			key := matrix[i][j].key()
+			if _, ok := symbols[key]; ok {
+				continue
+			}
+			label := matrix[i][j].String()
+			if shown[label] {
+				label = key
+			}
+			for shown[label] {
+				label += "'"
+			}
+			shown[label] = true
+			symbols[key] = len(labels)
+			labels = append(labels, label)
+		}
+	}
+
+	g := newAutomaton(labels)
+	index := make([]int, n)
+	for i, name := range names {
+		if !removed[i] {
+			index[i] = g.addState(name, i == n-1)
+		}
+	}
+	g.initial = index[0]
+	for i := range matrix {
+		for j := range matrix {
+			if removed[i] || removed[j] || matrix[i][j].Op == RegexEmpty {
+				continue
+			}
+			g.addTransition(index[i], symbols[matrix[i][j].key()], index[j])
+		}
+	}
+	return g.toFA()
+}
@@ -2,18 +2,18 @@ package logic
 
 import (
 	"fmt"
-	"log"
 	"slices"
-	"sort"
+	"strconv"
 	"strings"
 )
 
 type BooleanMode string
 
 const (
-	Union        BooleanMode = "union"
-	Intersection BooleanMode = "intersection"
-	// Difference   BooleanMode = "difference" // planned support on the near future
+	Union               BooleanMode = "union"
+	Intersection        BooleanMode = "intersection"
+	Difference          BooleanMode = "difference"           // first FA minus all others
+	SymmetricDifference BooleanMode = "symmetric_difference" // odd number of FAs accept
 )
 
 // FA represents a finite automaton.
@@ -31,946 +31,527 @@ func PerformBoolean(fas []*FA, mode BooleanMode) (*FA, error) {
 		return nil, fmt.Errorf("need at least two FAs for %s", mode)
 	}
 
-	// Verify all alphabets are identical
-	baseAlphabet := fas[0].Alphabet
-	for i := 1; i < len(fas); i++ {
-		if len(fas[i].Alphabet) != len(baseAlphabet) {
-			return nil, fmt.Errorf("alphabets differ")
-		}
-		for j := range baseAlphabet {
-			if fas[i].Alphabet[j] != baseAlphabet[j] {
-				return nil, fmt.Errorf("alphabets differ")
-			}
-		}
+	accept, err := mode.acceptance()
+	if err != nil {
+		return nil, err
 	}
 
-	// Build product states
-	newStates := []string{}
-	stateIndexMap := make(map[string]int)
+	return booleanProduct(fas, accept)
+}
 
-	// Generate all combinations of states
-	var generateStates func([]string, int)
-	generateStates = func(current []string, faIndex int) {
-		if faIndex == len(fas) {
-			newState := strings.Join(current, "|")
-			stateIndexMap[newState] = len(newStates)
-			newStates = append(newStates, newState)
-			return
-		}
-		for _, state := range fas[faIndex].States {
-			generateStates(append(current, state), faIndex+1)
-		}
+// booleanProduct builds the deterministic product of fas accepting the tuples
+// selected by accept. Nondeterministic operands are determinized first.
+func booleanProduct(fas []*FA, accept func([]bool) bool) (*FA, error) {
+	automata, err := compileAll(fas)
+	if err != nil {
+		return nil, err
 	}
-	generateStates([]string{}, 0)
-
-	// Initial state is combination of all initial states
-	initialParts := make([]string, len(fas))
-	for i, fa := range fas {
-		initialParts[i] = fa.Initial
-	}
-	newInitial := strings.Join(initialParts, "|")
-
-	// Acceptance states - FIXED LOGIC
-	newAcceptance := []string{}
-	for _, state := range newStates {
-		parts := strings.Split(state, "|")
-		if len(parts) == len(fas) {
-			switch mode {
-			case Intersection:
-				// For intersection: accept if ALL component states are accepting
-				all := true
-				for i, part := range parts {
-					if !Contains(fas[i].Acceptance, part) {
-						all = false
-						break
-					}
-				}
-				if all {
-					newAcceptance = append(newAcceptance, state)
-				}
-			case Union:
-				// For union: accept if ANY component state is accepting
-				any := false
-				for i, part := range parts {
-					if Contains(fas[i].Acceptance, part) {
-						any = true
-						break
-					}
-				}
-				if any {
-					newAcceptance = append(newAcceptance, state)
-				}
-			}
-		}
-	}
-
-	// Build transitions
-	newTransitions := make([][]any, len(newStates))
-	for i, state := range newStates {
-		parts := strings.Split(state, "|")
-		if len(parts) != len(fas) {
-			continue
-		}
-
-		row := make([]any, len(baseAlphabet))
-		for j := range baseAlphabet {
-			// Get next states from all FAs
-			nextParts := make([]any, len(fas))
-			for k, part := range parts {
-				nextParts[k] = getNextState(fas[k], part, j)
-			}
-
-			// Combine next states
-			combinedNext := combineBooleanStates(nextParts)
-			if combinedNext == "@v" {
-				row[j] = "@v"
-			} else if nextStates, ok := combinedNext.([]string); ok {
-				var validNextStates []string
-				for _, ns := range nextStates {
-					if Contains(newStates, ns) {
-						validNextStates = append(validNextStates, ns)
-					}
-				}
-				if len(validNextStates) == 0 {
-					row[j] = "@v"
-				} else if len(validNextStates) == 1 {
-					row[j] = validNextStates[0]
-				} else {
-					row[j] = validNextStates
-				}
-			} else if nextState, ok := combinedNext.(string); ok {
-				if Contains(newStates, nextState) {
-					row[j] = nextState
-				} else {
-					row[j] = "@v"
-				}
-			} else {
-				row[j] = "@v"
-			}
+	automata = alignAlphabets(automata)
+	for i, a := range automata {
+		if !a.isDeterministic() {
+			automata[i], _ = determinize(a)
 		}
-		newTransitions[i] = row
 	}
 
-	return &FA{
-		Alphabet:    baseAlphabet,
-		States:      newStates,
-		Initial:     newInitial,
-		Acceptance:  newAcceptance,
-		Transitions: newTransitions,
-	}, nil
+	return product(automata, accept).toFA(), nil
 }
 
 // NPerformBoolean applies a non-deterministic boolean operation to multiple FAs and returns the resulting FA.
-func NPerformBoolean(fas []*FA, mode BooleanMode) (*FA, error) {
+// For unions the states are renumbered and the returned provenance maps each of
+// them back to the FA and state it was copied from; intersections name their
+// states after the tuples they stand for and return no provenance.
+func NPerformBoolean(fas []*FA, mode BooleanMode) (*FA, Provenance, error) {
 	if len(fas) < 2 {
-		return nil, fmt.Errorf("need at least two FAs for %s", mode)
+		return nil, nil, fmt.Errorf("need at least two FAs for %s", mode)
 	}
 
-	// Verify all alphabets are identical
-	baseAlphabet := fas[0].Alphabet
-	for i := 1; i < len(fas); i++ {
-		if len(fas[i].Alphabet) != len(baseAlphabet) {
-			return nil, fmt.Errorf("alphabets differ")
-		}
-		for j := range baseAlphabet {
-			if fas[i].Alphabet[j] != baseAlphabet[j] {
-				return nil, fmt.Errorf("alphabets differ")
-			}
-		}
+	automata, err := compileAll(fas)
+	if err != nil {
+		return nil, nil, err
 	}
-
-	var newStates []string
-	var newInitial string
-	var newAcceptance []string
-	var newTransitions [][]any
+	automata = alignAlphabets(automata)
 
 	switch mode {
 	case Union:
-		// Create a new initial state
-		newInitial = "S"
-		newStates = []string{newInitial}
-
-		// Add all states from all FAs without renaming
-		for _, fa := range fas {
-			newStates = append(newStates, fa.States...)
-		}
-
-		// Find epsilon symbol index or add it if it doesn't exist
-		epsilonIdx := -1
-		for i, symbol := range baseAlphabet {
-			if symbol == "@e" {
-				epsilonIdx = i
-				break
-			}
-		}
-
-		// If epsilon doesn't exist, add it to the alphabet
-		if epsilonIdx == -1 {
-			baseAlphabet = append(baseAlphabet, "@e")
-			epsilonIdx = len(baseAlphabet) - 1
-		}
-
-		// Build transitions
-		newTransitions = make([][]any, len(newStates))
-
-		// Initial state transitions: epsilon to all FA initial states
-		initialRow := make([]any, len(baseAlphabet))
-		for j := range baseAlphabet {
-			if j == epsilonIdx {
-				// Epsilon transitions to all FA initial states
-				epsilonTargets := make([]string, len(fas))
-				for faIdx, fa := range fas {
-					epsilonTargets[faIdx] = fa.Initial
-				}
-				initialRow[j] = epsilonTargets
-			} else {
-				initialRow[j] = "@v"
-			}
-		}
-		newTransitions[0] = initialRow
-
-		// Copy transitions from all FAs
-		stateIndex := 1
-		for _, fa := range fas {
-			for i := range fa.States {
-				row := make([]any, len(baseAlphabet))
-				for j := range baseAlphabet {
-					if j < len(fa.Transitions[i]) {
-						originalNext := fa.Transitions[i][j]
-						row[j] = originalNext
-					} else {
-						// New epsilon column for existing FAs
-						row[j] = "@v"
-					}
-				}
-				newTransitions[stateIndex] = row
-				stateIndex++
-			}
-		}
-
-		// Acceptance states are all acceptance states from all FAs
-		for _, fa := range fas {
-			newAcceptance = append(newAcceptance, fa.Acceptance...)
-		}
+		// New initial state with epsilon transitions to every FA's initial state
+		result := newAutomaton(automata[0].alphabet)
+		result.initial = result.addState("q0", false)
+		provenance := Provenance{}
+		for i, a := range automata {
+			offset := result.embedDisjoint(a, i, provenance)
+			result.addEpsilon(result.initial, offset+a.initial)
+		}
+		return result.toFA(), provenance, nil
+	case Intersection:
+		return nfaIntersection(automata).toFA(), nil, nil
+	default:
+		return nil, nil, fmt.Errorf("unsupported non-deterministic boolean mode %q", mode)
 	}
-
-	log.Print(&FA{
-		Alphabet:    baseAlphabet,
-		States:      newStates,
-		Initial:     newInitial,
-		Acceptance:  newAcceptance,
-		Transitions: newTransitions,
-	})
-
-	return &FA{
-		Alphabet:    baseAlphabet,
-		States:      newStates,
-		Initial:     newInitial,
-		Acceptance:  newAcceptance,
-		Transitions: newTransitions,
-	}, nil
 }
 
-// Concatenation creates an NFA representing the concatenation of multiple NFAs
-func Concatenation(fas []*FA) (*FA, error) {
+// Concatenation creates an NFA representing the concatenation of multiple NFAs.
+// States are renumbered and the returned provenance maps each of them back to
+// the FA and state it was copied from.
+func Concatenation(fas []*FA) (*FA, Provenance, error) {
 	if len(fas) == 0 {
-		return nil, fmt.Errorf("need at least one FA for concatenation")
-	}
-	if len(fas) == 1 {
-		return fas[0], nil
-	}
-
-	// Verify all alphabets are identical
-	baseAlphabet := fas[0].Alphabet
-	for i := 1; i < len(fas); i++ {
-		if len(fas[i].Alphabet) != len(baseAlphabet) {
-			return nil, fmt.Errorf("alphabets differ")
-		}
-		for j := range baseAlphabet {
-			if fas[i].Alphabet[j] != baseAlphabet[j] {
-				return nil, fmt.Errorf("alphabets differ")
+		return nil, nil, fmt.Errorf("need at least one FA for concatenation")
+	}
+	automata, err := compileAll(fas)
+	if err != nil {
+		return nil, nil, err
+	}
+	automata = alignAlphabets(automata)
+
+	result := newAutomaton(automata[0].alphabet)
+	provenance := Provenance{}
+	offsets := make([]int, len(automata))
+	for i, a := range automata {
+		offsets[i] = result.embedDisjoint(a, i, provenance)
+	}
+	result.initial = offsets[0] + automata[0].initial
+
+	// Accepting states of every FA but the last lose acceptance and gain an
+	// epsilon transition to the next FA's initial state
+	for i, a := range automata[:len(automata)-1] {
+		next := offsets[i+1] + automata[i+1].initial
+		for q := range a.states {
+			if a.accept[q] {
+				result.accept[offsets[i]+q] = false
+				result.addEpsilon(offsets[i]+q, next)
 			}
 		}
 	}
 
-	// Collect all states from all FAs without renaming
-	var newStates []string
-	for _, fa := range fas {
-		newStates = append(newStates, fa.States...)
-	}
+	return result.toFA(), provenance, nil
+}
 
-	// Find epsilon symbol index or add it if it doesn't exist
-	epsilonIdx := -1
-	for i, symbol := range baseAlphabet {
-		if symbol == "@e" {
-			epsilonIdx = i
-			break
+// compileAll compiles every FA, reporting which one failed.
+func compileAll(fas []*FA) ([]*automaton, error) {
+	automata := make([]*automaton, len(fas))
+	for i, fa := range fas {
+		a, err := compile(fa)
+		if err != nil {
+			return nil, fmt.Errorf("FA %d: %v", i+1, err)
 		}
+		automata[i] = a
 	}
+	return automata, nil
+}
 
-	// If epsilon doesn't exist, add it to the alphabet
-	if epsilonIdx == -1 {
-		baseAlphabet = append(baseAlphabet, "@e")
-		epsilonIdx = len(baseAlphabet) - 1
-	}
-
-	// Build transitions
-	newTransitions := make([][]any, len(newStates))
-	stateIndex := 0
-
-	for faIdx, fa := range fas {
-		for i, state := range fa.States {
-			row := make([]any, len(baseAlphabet))
-
-			for j := range baseAlphabet {
-				if j < len(fa.Transitions[i]) {
-					originalNext := fa.Transitions[i][j]
-					row[j] = originalNext
-				} else {
-					// New epsilon column for existing FAs
-					row[j] = "@v"
-				}
-			}
-
-			// If this is an accepting state and not the last FA,
-			// add epsilon transition to next FA's initial state
-			isAccepting := Contains(fa.Acceptance, state)
-			isLastFA := faIdx == len(fas)-1
-
-			if isAccepting && !isLastFA && epsilonIdx >= 0 {
-				nextFAInitial := fas[faIdx+1].Initial
-
-				// Handle existing epsilon transitions
-				if row[epsilonIdx] == "@v" {
-					row[epsilonIdx] = nextFAInitial
-				} else {
-					// Combine with existing epsilon transitions
-					switch v := row[epsilonIdx].(type) {
-					case string:
-						if v != nextFAInitial {
-							row[epsilonIdx] = []string{v, nextFAInitial}
-						}
-					case []string:
-						found := slices.Contains(v, nextFAInitial)
-						if !found {
-							row[epsilonIdx] = append(v, nextFAInitial)
-						}
-					}
-				}
+// alignAlphabets rewrites the automata over the union of their alphabets,
+// taken in order of first appearance, matching transition columns by symbol
+// name. Symbols an automaton lacks simply have no transitions in it, which
+// product treats as moving to a sink.
+func alignAlphabets(automata []*automaton) []*automaton {
+	var alphabet []string
+	seen := make(map[string]bool)
+	for _, a := range automata {
+		for _, symbol := range a.alphabet {
+			if !seen[symbol] {
+				seen[symbol] = true
+				alphabet = append(alphabet, symbol)
 			}
-
-			newTransitions[stateIndex] = row
-			stateIndex++
 		}
 	}
 
-	// Initial state is the first FA's initial state
-	newInitial := fas[0].Initial
-
-	// Acceptance states are only from the last FA
-	lastFAIdx := len(fas) - 1
-	newAcceptance := append([]string{}, fas[lastFAIdx].Acceptance...)
-
-	return &FA{
-		Alphabet:    baseAlphabet,
-		States:      newStates,
-		Initial:     newInitial,
-		Acceptance:  newAcceptance,
-		Transitions: newTransitions,
-	}, nil
+	aligned := make([]*automaton, len(automata))
+	for i, a := range automata {
+		aligned[i] = a.withAlphabet(alphabet)
+	}
+	return aligned
 }
 
-// combineBooleanStates combines next states for intersection
-func combineBooleanStates(nextParts []any) any {
-	// Convert all parts to state slices
-	stateSets := make([][]string, len(nextParts))
-	for i, part := range nextParts {
-		stateSets[i] = interfaceToStateSlice(part)
-		if len(stateSets[i]) == 0 {
-			return "@v" // If any FA has no next state, boolean operation results in empty
+// withAlphabet returns a copy of the automaton over alphabet, which must
+// contain every symbol of the automaton's own alphabet.
+func (a *automaton) withAlphabet(alphabet []string) *automaton {
+	result := newAutomaton(alphabet)
+	for q, name := range a.states {
+		result.addState(name, a.accept[q])
+		for symbol, targets := range a.delta[q] {
+			result.delta[q][result.symbols[a.alphabet[symbol]]] = append([]int{}, targets...)
 		}
+		result.eps[q] = append([]int{}, a.eps[q]...)
 	}
-
-	// Generate all combinations
-	var result []string
-	var generateCombinations func([]string, int)
-	generateCombinations = func(current []string, index int) {
-		if index == len(stateSets) {
-			result = append(result, strings.Join(current, "|"))
-			return
-		}
-		for _, state := range stateSets[index] {
-			generateCombinations(append(current, state), index+1)
-		}
-	}
-	generateCombinations([]string{}, 0)
-
-	if len(result) == 0 {
-		return "@v"
-	} else if len(result) == 1 {
-		return result[0]
-	} else {
-		return result
-	}
+	result.initial = a.initial
+	return result
 }
 
-// NFAToDFA converts an NFA to DFA using subset construction
-func NFAToDFA(nfa *FA) (*FA, error) {
-	// Find epsilon symbol index
-	epsilonIdx := -1
-	for i, symbol := range nfa.Alphabet {
-		if symbol == "@e" {
-			epsilonIdx = i
-			break
-		}
+// freshName returns base, primed as many times as needed to not name a state.
+func (a *automaton) freshName(base string) string {
+	for Contains(a.states, base) {
+		base += "'"
 	}
+	return base
+}
 
-	// Helper function to compute epsilon closure
-	epsilonClosure := func(states []string) []string {
-		closure := make(map[string]bool)
-		stack := make([]string, len(states))
-		copy(stack, states)
-
-		for _, s := range states {
-			closure[s] = true
-		}
-
-		for len(stack) > 0 {
-			current := stack[len(stack)-1]
-			stack = stack[:len(stack)-1]
-
-			// Find epsilon transitions if epsilon symbol exists
-			if epsilonIdx >= 0 {
-				stateIdx := -1
-				for i, s := range nfa.States {
-					if s == current {
-						stateIdx = i
-						break
-					}
-				}
-
-				if stateIdx >= 0 && stateIdx < len(nfa.Transitions) &&
-					epsilonIdx < len(nfa.Transitions[stateIdx]) {
-					next := nfa.Transitions[stateIdx][epsilonIdx]
-
-					switch v := next.(type) {
-					case string:
-						if v != "" && v != "@v" && !closure[v] {
-							closure[v] = true
-							stack = append(stack, v)
-						}
-					case []string:
-						for _, ns := range v {
-							if ns != "" && ns != "@v" && !closure[ns] {
-								closure[ns] = true
-								stack = append(stack, ns)
-							}
-						}
-					case []any:
-						for _, item := range v {
-							if s, ok := item.(string); ok && s != "" && s != "@v" && !closure[s] {
-								closure[s] = true
-								stack = append(stack, s)
-							}
-						}
-					}
-				}
+// embed copies every state and transition of b into a and returns the index
+// offset at which b's states were placed.
+func (a *automaton) embed(b *automaton) int {
+	offset := len(a.states)
+	for q, name := range b.states {
+		a.addState(name, b.accept[q])
+	}
+	for q := range b.states {
+		for symbol, targets := range b.delta[q] {
+			for _, p := range targets {
+				a.addTransition(offset+q, symbol, offset+p)
 			}
 		}
-
-		result := make([]string, 0, len(closure))
-		for state := range closure {
-			result = append(result, state)
+		for _, p := range b.eps[q] {
+			a.addEpsilon(offset+q, offset+p)
 		}
-		sort.Strings(result)
-		return result
 	}
+	return offset
+}
 
-	// Start with initial state closure
-	initialClosure := epsilonClosure([]string{nfa.Initial})
-
-	// DFA states will be sets of NFA states
-	dfaStates := [][]string{initialClosure}
-	stateToIndex := make(map[string]int)
-	stateToIndex[strings.Join(initialClosure, ",")] = 0
-
-	// Create DFA alphabet without epsilon
-	dfaAlphabet := make([]string, 0)
-	for i, symbol := range nfa.Alphabet {
-		if i != epsilonIdx {
-			dfaAlphabet = append(dfaAlphabet, symbol)
-		}
-	}
-
-	// Build DFA states and transitions
-	dfaTransitions := [][]any{}
-	queue := [][]string{initialClosure}
-	processed := make(map[string]bool)
-	needsTrashState := false
-
-	for len(queue) > 0 {
-		currentSet := queue[0]
-		queue = queue[1:]
-
-		currentKey := strings.Join(currentSet, ",")
-		if processed[currentKey] {
-			continue
-		}
-		processed[currentKey] = true
-
-		row := make([]any, len(dfaAlphabet))
+// Origin identifies the state of an input FA that a combined state was copied from.
+type Origin struct {
+	Operand int    `json:"operand"` // position of the FA among the operands
+	State   string `json:"state"`
+}
 
-		for dfaSymbolIdx, symbol := range dfaAlphabet {
-			// Find original symbol index in NFA
-			nfaSymbolIdx := -1
-			for i, nfaSymbol := range nfa.Alphabet {
-				if nfaSymbol == symbol {
-					nfaSymbolIdx = i
-					break
-				}
-			}
+// Provenance maps the state names of a combined FA to their origin. States
+// introduced by the operation itself have no entry.
+type Provenance map[string]Origin
+
+// embedDisjoint embeds b like embed but names every copied state q<i> after
+// its index in a, so that operands sharing state names cannot collide, and
+// records the original name of each copy in provenance.
+func (a *automaton) embedDisjoint(b *automaton, operand int, provenance Provenance) int {
+	offset := a.embed(b)
+	for q, name := range b.states {
+		renamed := fmt.Sprintf("q%d", offset+q)
+		a.states[offset+q] = renamed
+		provenance[renamed] = Origin{Operand: operand, State: name}
+	}
+	return offset
+}
 
-			if nfaSymbolIdx == -1 {
-				row[dfaSymbolIdx] = "@t"
-				needsTrashState = true
+// product builds the synchronous product of deterministic automata sharing an
+// alphabet, exploring only the tuples reachable from the initial tuple. accept
+// decides whether a tuple is accepting from the acceptance of its components.
+// A component lacking a transition moves to an implicit non-accepting sink,
+// written @t in state names (primed if the component has a state named so),
+// so that every mode sees complete operands. The tuple in which every
+// component is dead is only kept when it is accepting.
+func product(automata []*automaton, accept func([]bool) bool) *automaton {
+	result := newAutomaton(automata[0].alphabet)
+	index := make(map[string]int)
+	var tuples [][]int
+
+	sinks := make([]string, len(automata))
+	for i, a := range automata {
+		sinks[i] = a.freshName(trapState)
+	}
+
+	visit := func(tuple []int) int {
+		key := tupleKey(tuple)
+		if p, ok := index[key]; ok {
+			return p
+		}
+		names := make([]string, len(tuple))
+		flags := make([]bool, len(tuple))
+		for i, q := range tuple {
+			if q < 0 {
+				names[i] = sinks[i]
 				continue
 			}
-
-			// Compute next state set
-			nextStates := make(map[string]bool)
-
-			for _, state := range currentSet {
-				stateIdx := -1
-				for i, s := range nfa.States {
-					if s == state {
-						stateIdx = i
-						break
-					}
-				}
-
-				if stateIdx >= 0 && stateIdx < len(nfa.Transitions) &&
-					nfaSymbolIdx < len(nfa.Transitions[stateIdx]) {
-					next := nfa.Transitions[stateIdx][nfaSymbolIdx]
-
-					switch v := next.(type) {
-					case string:
-						if v != "" && v != "@v" {
-							nextStates[v] = true
-						}
-					case []string:
-						for _, ns := range v {
-							if ns != "" && ns != "@v" {
-								nextStates[ns] = true
-							}
-						}
-					case []any:
-						for _, item := range v {
-							if s, ok := item.(string); ok && s != "" && s != "@v" {
-								nextStates[s] = true
-							}
-						}
-					}
+			names[i] = automata[i].states[q]
+			flags[i] = automata[i].accept[q]
+		}
+		p := result.addState(strings.Join(names, "|"), accept(flags))
+		index[key] = p
+		tuples = append(tuples, tuple)
+		return p
+	}
+
+	initial := make([]int, len(automata))
+	for i, a := range automata {
+		initial[i] = a.initial
+	}
+	result.initial = visit(initial)
+
+	deadAccepts := accept(make([]bool, len(automata)))
+	for p := 0; p < len(tuples); p++ {
+		for symbol := range result.alphabet {
+			next := make([]int, len(automata))
+			alive := false
+			for i, q := range tuples[p] {
+				next[i] = -1
+				if q >= 0 {
+					next[i] = automata[i].next(q, symbol)
 				}
+				alive = alive || next[i] >= 0
 			}
-
-			if len(nextStates) == 0 {
-				row[dfaSymbolIdx] = "@t"
-				needsTrashState = true
-			} else {
-				nextStateSlice := make([]string, 0, len(nextStates))
-				for state := range nextStates {
-					nextStateSlice = append(nextStateSlice, state)
-				}
-				sort.Strings(nextStateSlice)
-
-				nextClosure := epsilonClosure(nextStateSlice)
-				nextKey := strings.Join(nextClosure, ",")
-
-				if _, exists := stateToIndex[nextKey]; !exists {
-					stateToIndex[nextKey] = len(dfaStates)
-					dfaStates = append(dfaStates, nextClosure)
-					queue = append(queue, nextClosure)
-				}
-
-				row[dfaSymbolIdx] = nextKey
+			if alive || deadAccepts {
+				result.addTransition(p, symbol, visit(next))
 			}
 		}
-		dfaTransitions = append(dfaTransitions, row)
 	}
 
-	// Convert state sets to state names and build final DFA
-	dfaStateNames := make([]string, len(dfaStates))
-	for i := range dfaStates {
-		dfaStateNames[i] = fmt.Sprintf("q%d", i)
-	}
+	return result
+}
 
-	// Add trash state if needed
-	if needsTrashState {
-		dfaStateNames = append(dfaStateNames, "@t")
-		// Create transitions for trash state - all transitions go to itself
-		trashRow := make([]any, len(dfaAlphabet))
-		for j := range trashRow {
-			trashRow[j] = "@t"
-		}
-		dfaTransitions = append(dfaTransitions, trashRow)
-	}
-
-	// Update transitions to use new state names
-	finalTransitions := make([][]any, len(dfaTransitions))
-	for i, row := range dfaTransitions {
-		finalRow := make([]any, len(row))
-		for j, transition := range row {
-			if transition == "@t" {
-				finalRow[j] = "@t"
-			} else if stateKey, ok := transition.(string); ok {
-				if idx, exists := stateToIndex[stateKey]; exists {
-					finalRow[j] = dfaStateNames[idx]
-				} else {
-					finalRow[j] = "@t"
-				}
+// nfaIntersection builds the product of nondeterministic automata sharing an
+// alphabet, accepting the tuples whose components all accept. On a symbol a
+// tuple moves to every combination of its components' targets; an epsilon
+// transition of one component moves that component alone, so the epsilon
+// closure of a tuple is the product of its components' closures. Only tuples
+// reachable from the initial tuple are built.
+func nfaIntersection(automata []*automaton) *automaton {
+	result := newAutomaton(automata[0].alphabet)
+	index := make(map[string]int)
+	var tuples [][]int
+
+	visit := func(tuple []int) int {
+		key := tupleKey(tuple)
+		if p, ok := index[key]; ok {
+			return p
+		}
+		names := make([]string, len(tuple))
+		accepting := true
+		for i, q := range tuple {
+			names[i] = automata[i].states[q]
+			accepting = accepting && automata[i].accept[q]
+		}
+		p := result.addState(strings.Join(names, "|"), accepting)
+		index[key] = p
+		tuples = append(tuples, tuple)
+		return p
+	}
+
+	initial := make([]int, len(automata))
+	for i, a := range automata {
+		initial[i] = a.initial
+	}
+	result.initial = visit(initial)
+
+	for p := 0; p < len(tuples); p++ {
+		tuple := tuples[p]
+		for symbol := range result.alphabet {
+			targets := make([][]int, len(automata))
+			for i, q := range tuple {
+				targets[i] = automata[i].delta[q][symbol]
 			}
-		}
-		finalTransitions[i] = finalRow
-	}
-
-	// Determine acceptance states
-	dfaAcceptance := []string{}
-	for i, stateSet := range dfaStates {
-		for _, nfaState := range stateSet {
-			if Contains(nfa.Acceptance, nfaState) {
-				dfaAcceptance = append(dfaAcceptance, dfaStateNames[i])
-				break
+			for _, next := range combinations(targets) {
+				result.addTransition(p, symbol, visit(next))
 			}
 		}
-	}
-
-	return &FA{
-		Alphabet:    dfaAlphabet,
-		States:      dfaStateNames,
-		Initial:     dfaStateNames[0],
-		Acceptance:  dfaAcceptance,
-		Transitions: finalTransitions,
-	}, nil
-}
-
-// Complement returns the complement of an FA (flip acceptance states)
-func Complement(fa *FA) *FA {
-	newAcceptance := []string{}
-
-	for _, state := range fa.States {
-		if !Contains(fa.Acceptance, state) {
-			newAcceptance = append(newAcceptance, state)
-		}
-	}
-
-	// Create a copy of the FA with flipped acceptance states
-	newTransitions := make([][]any, len(fa.Transitions))
-	for i, row := range fa.Transitions {
-		newRow := make([]any, len(row))
-		copy(newRow, row)
-		newTransitions[i] = newRow
-	}
-
-	return &FA{
-		Alphabet:    append([]string{}, fa.Alphabet...),
-		States:      append([]string{}, fa.States...),
-		Initial:     fa.Initial,
-		Acceptance:  newAcceptance,
-		Transitions: newTransitions,
-	}
-}
-
-// MinimizeDFA minimizes a DFA using Hopcroft's algorithm
-func MinimizeDFA(dfa *FA) (*FA, error) {
-	n := len(dfa.States)
-	if n <= 1 {
-		return dfa, nil
-	}
-
-	log.Print(dfa)
-	log.Print("==============")
-
-	// First, remove inaccessible states
-	accessible := make(map[string]bool)
-	queue := []string{dfa.Initial}
-	accessible[dfa.Initial] = true
-
-	// BFS to find all accessible states
-	for len(queue) > 0 {
-		current := queue[0]
-		queue = queue[1:]
-
-		currentIdx := getStateIndex(dfa, current)
-		if currentIdx == -1 || currentIdx >= len(dfa.Transitions) {
-			continue
-		}
-
-		for _, transition := range dfa.Transitions[currentIdx] {
-			switch v := transition.(type) {
-			case string:
-				if v != "@v" && !accessible[v] {
-					accessible[v] = true
-					queue = append(queue, v)
-				}
-			case []string:
-				for _, state := range v {
-					if state != "@v" && !accessible[state] {
-						accessible[state] = true
-						queue = append(queue, state)
-					}
-				}
+		for i, q := range tuple {
+			for _, r := range automata[i].eps[q] {
+				next := append([]int{}, tuple...)
+				next[i] = r
+				result.addEpsilon(p, visit(next))
 			}
 		}
 	}
 
-	// Create new DFA with only accessible states
-	accessibleStates := make([]string, 0)
-	oldToAccessible := make(map[int]int)
-	
-	for i, state := range dfa.States {
-		if accessible[state] {
-			oldToAccessible[i] = len(accessibleStates)
-			accessibleStates = append(accessibleStates, state)
-		}
-	}
+	return result
+}
 
-	// Build transitions for accessible states only
-	accessibleTransitions := make([][]any, len(accessibleStates))
-	for i, state := range accessibleStates {
-		oldIdx := getStateIndex(dfa, state)
-		if oldIdx == -1 || oldIdx >= len(dfa.Transitions) {
-			continue
-		}
-		
-		row := make([]any, len(dfa.Alphabet))
-		for j, transition := range dfa.Transitions[oldIdx] {
-			switch v := transition.(type) {
-			case string:
-				if v == "@v" || !accessible[v] {
-					row[j] = "@v"
-				} else {
-					row[j] = v
-				}
-			case []string:
-				validStates := make([]string, 0)
-				for _, state := range v {
-					if state != "@v" && accessible[state] {
-						validStates = append(validStates, state)
-					}
-				}
-				if len(validStates) == 0 {
-					row[j] = "@v"
-				} else if len(validStates) == 1 {
-					row[j] = validStates[0]
-				} else {
-					row[j] = validStates
-				}
-			default:
-				row[j] = "@v"
+// combinations returns every tuple picking one element of each choice list.
+func combinations(choices [][]int) [][]int {
+	result := [][]int{{}}
+	for _, options := range choices {
+		var extended [][]int
+		for _, prefix := range result {
+			for _, option := range options {
+				extended = append(extended, append(append([]int{}, prefix...), option))
 			}
 		}
-		accessibleTransitions[i] = row
-	}
-
-	// Build accessible acceptance states
-	accessibleAcceptance := make([]string, 0)
-	for _, state := range dfa.Acceptance {
-		if accessible[state] {
-			accessibleAcceptance = append(accessibleAcceptance, state)
-		}
+		result = extended
 	}
+	return result
+}
 
-	// Create temporary DFA with only accessible states
-	accessibleDFA := &FA{
-		Alphabet:    dfa.Alphabet,
-		States:      accessibleStates,
-		Initial:     dfa.Initial,
-		Acceptance:  accessibleAcceptance,
-		Transitions: accessibleTransitions,
+// tupleKey encodes a tuple of state indices as a map key.
+func tupleKey(tuple []int) string {
+	var b strings.Builder
+	for _, q := range tuple {
+		b.WriteString(strconv.Itoa(q))
+		b.WriteByte(',')
 	}
+	return b.String()
+}
 
-	// Now proceed with Hopcroft's algorithm on accessible states
-	n = len(accessibleDFA.States)
-	if n <= 1 {
-		return accessibleDFA, nil
+// NFAToDFA converts an NFA to DFA using subset construction
+func NFAToDFA(nfa *FA) (*FA, error) {
+	a, err := compile(nfa)
+	if err != nil {
+		return nil, err
 	}
+	dfa, _ := determinize(a)
+	return dfa.toFA(), nil
+}
 
-	// Partition states into accepting and non-accepting
-	accepting := make([]int, 0)
-	nonAccepting := make([]int, 0)
+// SubsetTrace records a run of the subset construction. Subsets maps every
+// DFA state to the NFA states it stands for, the trap state @t to none.
+type SubsetTrace struct {
+	Start   string              `json:"start"`   // the NFA initial state
+	Closure []string            `json:"closure"` // its epsilon closure, the subset of q0
+	Steps   []SubsetStep        `json:"steps"`
+	Subsets map[string][]string `json:"subsets"`
+}
 
-	for i, state := range accessibleDFA.States {
-		if Contains(accessibleDFA.Acceptance, state) {
-			accepting = append(accepting, i)
-		} else {
-			nonAccepting = append(nonAccepting, i)
-		}
-	}
+// SubsetStep is the processing of one DFA state, in discovery order.
+type SubsetStep struct {
+	State  string       `json:"state"`
+	Subset []string     `json:"subset"`
+	Moves  []SubsetMove `json:"moves"`
+}
 
-	// Initial partition
-	partition := make([][]int, 0)
-	if len(nonAccepting) > 0 {
-		partition = append(partition, nonAccepting)
-	}
-	if len(accepting) > 0 {
-		partition = append(partition, accepting)
-	}
+// SubsetMove is the successor of a subset on one symbol: the NFA states the
+// symbol leads to, their epsilon closure and the DFA state standing for it.
+type SubsetMove struct {
+	Symbol  string   `json:"symbol"`
+	Move    []string `json:"move"`
+	Closure []string `json:"closure"`
+	Target  string   `json:"target"`
+	New     bool     `json:"new"` // Target is first discovered by this move
+}
 
-	// Create work list with all alphabet symbols for each partition
-	workList := make([][]int, 0)
-	for _, block := range partition {
-		for range accessibleDFA.Alphabet {
-			workList = append(workList, block)
-		}
-	}
+// NFAToDFATrace converts an NFA to DFA like NFAToDFA and also returns every
+// step of the subset construction.
+func NFAToDFATrace(nfa *FA) (*FA, *SubsetTrace, error) {
+	a, err := compile(nfa)
+	if err != nil {
+		return nil, nil, err
+	}
+	dfa, subsets := determinize(a)
+
+	members := func(set stateSet) []string {
+		names := []string{}
+		for _, q := range set.members() {
+			names = append(names, a.states[q])
+		}
+		return names
+	}
+
+	trace := &SubsetTrace{
+		Start:   a.states[a.initial],
+		Closure: members(subsets[dfa.initial]),
+		Subsets: make(map[string][]string, len(subsets)),
+	}
+	seen := make([]bool, len(dfa.states))
+	seen[dfa.initial] = true
+	for p, subset := range subsets {
+		trace.Subsets[dfa.states[p]] = members(subset)
+		step := SubsetStep{State: dfa.states[p], Subset: members(subset)}
+		for symbol, name := range a.alphabet {
+			move := a.move(subset, symbol)
+			closure := slices.Clone(move)
+			a.closure(closure)
+			target := dfa.next(p, symbol)
+			step.Moves = append(step.Moves, SubsetMove{
+				Symbol:  name,
+				Move:    members(move),
+				Closure: members(closure),
+				Target:  dfa.states[target],
+				New:     !seen[target],
+			})
+			seen[target] = true
+		}
+		trace.Steps = append(trace.Steps, step)
+	}
+	return dfa.toFA(), trace, nil
+}
 
-	// Hopcroft's algorithm main loop
-	for len(workList) > 0 {
-		// Pop from work list
-		splitter := workList[0]
-		workList = workList[1:]
-
-		for symbolIdx := range accessibleDFA.Alphabet {
-			// Find states that transition to splitter on this symbol
-			predecessors := make([]int, 0)
-			for i, state := range accessibleDFA.States {
-				next := getNextState(accessibleDFA, state, symbolIdx)
-				nextIdx := getStateIndex(accessibleDFA, next)
-				if nextIdx != -1 {
-					if slices.Contains(splitter, nextIdx) {
-						predecessors = append(predecessors, i)
-					}
-				}
-			}
+// determinize performs the subset construction. DFA states are named q0..qn in
+// discovery order, followed by the trap state @t when some subset has no
+// successor on a symbol. The returned subsets hold, for every DFA state, the
+// NFA states it stands for; the trap state stands for the empty set.
+func determinize(a *automaton) (*automaton, []stateSet) {
+	start := newStateSet(len(a.states))
+	start.add(a.initial)
+	dfa, subsets, _ := determinizeFrom(a, start, 0)
+	return dfa, subsets
+}
 
-			if len(predecessors) == 0 {
+// determinizeFrom performs the subset construction starting from the epsilon
+// closure of the given set of states instead of the initial state alone. It
+// gives up once more than limit subsets are found, unless limit is 0.
+func determinizeFrom(a *automaton, start stateSet, limit int) (*automaton, []stateSet, error) {
+	result := newAutomaton(a.alphabet)
+	index := make(map[string]int)
+	var subsets []stateSet
+	var err error
+
+	visit := func(set stateSet) int {
+		key := set.key()
+		if p, ok := index[key]; ok {
+			return p
+		}
+		if limit > 0 && len(subsets) >= limit {
+			err = fmt.Errorf("subset construction exceeds %d states", limit)
+			return -1
+		}
+		p := result.addState(fmt.Sprintf("q%d", len(subsets)), a.accepts(set))
+		index[key] = p
+		subsets = append(subsets, set)
+		return p
+	}
+
+	a.closure(start)
+	result.initial = visit(start)
+
+	// Transitions into the empty set are resolved once every subset is known,
+	// so that the trap state comes last, unless the empty set is where the
+	// construction starts
+	var toTrap [][2]int
+	for p := 0; p < len(subsets); p++ {
+		for symbol := range a.alphabet {
+			next := a.move(subsets[p], symbol)
+			a.closure(next)
+			if next.empty() && !start.empty() {
+				toTrap = append(toTrap, [2]int{p, symbol})
 				continue
 			}
-
-			// Split blocks that intersect with predecessors
-			newPartition := make([][]int, 0)
-			for _, block := range partition {
-				intersect := make([]int, 0)
-				difference := make([]int, 0)
-
-				for _, state := range block {
-					found := slices.Contains(predecessors, state)
-					if found {
-						intersect = append(intersect, state)
-					} else {
-						difference = append(difference, state)
-					}
-				}
-
-				if len(intersect) > 0 && len(difference) > 0 {
-					// Block was split
-					newPartition = append(newPartition, intersect)
-					newPartition = append(newPartition, difference)
-
-					// Update work list
-					newWorkList := make([][]int, 0)
-					for _, workItem := range workList {
-						if slicesEqual(workItem, block) {
-							// Replace with smaller block
-							if len(intersect) <= len(difference) {
-								newWorkList = append(newWorkList, intersect)
-							} else {
-								newWorkList = append(newWorkList, difference)
-							}
-						} else {
-							newWorkList = append(newWorkList, workItem)
-						}
-					}
-					workList = newWorkList
-
-					// Add new block to work list for all symbols
-					var smallerBlock []int
-					if len(intersect) <= len(difference) {
-						smallerBlock = intersect
-					} else {
-						smallerBlock = difference
-					}
-					for range accessibleDFA.Alphabet {
-						workList = append(workList, smallerBlock)
-					}
-				} else {
-					newPartition = append(newPartition, block)
-				}
+			target := visit(next)
+			if err != nil {
+				return nil, nil, err
 			}
-			partition = newPartition
+			result.addTransition(p, symbol, target)
 		}
 	}
 
-	// Build minimized DFA from partition
-	newStates := make([]string, len(partition))
-	oldToNew := make(map[int]int)
-
-	for i, block := range partition {
-		newStates[i] = fmt.Sprintf("q%d", i)
-		for _, oldIdx := range block {
-			oldToNew[oldIdx] = i
+	if len(toTrap) > 0 {
+		trap := result.addState(trapState, false)
+		subsets = append(subsets, newStateSet(len(a.states)))
+		for symbol := range a.alphabet {
+			result.addTransition(trap, symbol, trap)
 		}
-	}
-
-	// Build transitions
-	newTransitions := make([][]any, len(newStates))
-	for i, block := range partition {
-		representative := block[0]
-		row := make([]any, len(accessibleDFA.Alphabet))
-
-		for j := range accessibleDFA.Alphabet {
-			next := getNextState(accessibleDFA, accessibleDFA.States[representative], j)
-			nextIdx := getStateIndex(accessibleDFA, next)
-
-			if nextIdx == -1 {
-				row[j] = "@v"
-			} else {
-				row[j] = newStates[oldToNew[nextIdx]]
-			}
+		for _, t := range toTrap {
+			result.addTransition(t[0], t[1], trap)
 		}
-		newTransitions[i] = row
 	}
 
-	// Determine initial and acceptance states
-	initialIdx := getStateIndex(accessibleDFA, accessibleDFA.Initial)
-	newInitial := newStates[oldToNew[initialIdx]]
-
-	newAcceptance := []string{}
-	for i, block := range partition {
-		for _, oldIdx := range block {
-			if Contains(accessibleDFA.Acceptance, accessibleDFA.States[oldIdx]) {
-				newAcceptance = append(newAcceptance, newStates[i])
-				break
-			}
-		}
-	}
+	return result, subsets, nil
+}
 
-	log.Print(&FA{
-		Alphabet:    accessibleDFA.Alphabet,
-		States:      newStates,
-		Initial:     newInitial,
-		Acceptance:  newAcceptance,
-		Transitions: newTransitions,
-	})
-
-	return &FA{
-		Alphabet:    accessibleDFA.Alphabet,
-		States:      newStates,
-		Initial:     newInitial,
-		Acceptance:  newAcceptance,
-		Transitions: newTransitions,
-	}, nil
+// ComplementPreprocessing reports what Complement had to do to its input
+// before flipping acceptance.
+type ComplementPreprocessing struct {
+	Determinized bool `json:"determinized"` // the input was nondeterministic
+	Completed    bool `json:"completed"`    // a sink was added for missing transitions
 }
 
-// slicesEqual checks if two int slices are equal
-func slicesEqual(a, b []int) bool {
-	if len(a) != len(b) {
-		return false
-	}
-	for i := range a {
-		if a[i] != b[i] {
-			return false
-		}
-	}
-	return true
+// Complement returns a complete DFA accepting exactly the strings over the
+// alphabet of fa that fa rejects. Flipping acceptance is only sound on a
+// complete DFA, so nondeterministic FAs are determinized and partial ones
+// completed with a sink first, as reported in the returned preprocessing.
+func Complement(fa *FA) (*FA, ComplementPreprocessing, error) {
+	var preprocessing ComplementPreprocessing
+	a, err := compile(fa)
+	if err != nil {
+		return nil, preprocessing, err
+	}
+	if !a.isDeterministic() {
+		a, _ = determinize(a)
+		preprocessing.Determinized = true
+	}
+	preprocessing.Completed = a.complete()
+	for q := range a.accept {
+		a.accept[q] = !a.accept[q]
+	}
+	return a.toFA(), preprocessing, nil
 }
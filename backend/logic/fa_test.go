@@ -0,0 +1,1320 @@
+package logic
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// simulate runs word through fa by reading its transition table directly,
+// without compiling it, and serves as the reference the algorithms are
+// checked against. Symbols outside the alphabet reject.
+func simulate(fa *FA, word []string) bool {
+	targets := func(state, symbol string) []string {
+		q, c := slices.Index(fa.States, state), slices.Index(fa.Alphabet, symbol)
+		if q < 0 || c < 0 || q >= len(fa.Transitions) || c >= len(fa.Transitions[q]) {
+			return nil
+		}
+		return cellTargets(fa.Transitions[q][c])
+	}
+	closure := func(states []string) []string {
+		for i := 0; i < len(states); i++ {
+			for _, p := range targets(states[i], epsilonSymbol) {
+				if !slices.Contains(states, p) {
+					states = append(states, p)
+				}
+			}
+		}
+		return states
+	}
+
+	current := closure([]string{fa.Initial})
+	for _, symbol := range word {
+		var next []string
+		for _, q := range current {
+			for _, p := range targets(q, symbol) {
+				if !slices.Contains(next, p) {
+					next = append(next, p)
+				}
+			}
+		}
+		current = closure(next)
+	}
+	for _, q := range current {
+		if slices.Contains(fa.Acceptance, q) {
+			return true
+		}
+	}
+	return false
+}
+
+// randomFA returns an FA with up to four states over a random nonempty subset
+// of alphabet, with partial, nondeterministic and, if epsilon is set, epsilon
+// transitions. One state may be named @t to catch clashes with added sinks.
+func randomFA(r *rand.Rand, alphabet []string, epsilon bool) *FA {
+	fa := &FA{Acceptance: []string{}}
+	for _, symbol := range alphabet {
+		if r.Intn(3) > 0 {
+			fa.Alphabet = append(fa.Alphabet, symbol)
+		}
+	}
+	if len(fa.Alphabet) == 0 {
+		fa.Alphabet = []string{alphabet[r.Intn(len(alphabet))]}
+	}
+	if epsilon && r.Intn(2) == 0 {
+		fa.Alphabet = append(fa.Alphabet, epsilonSymbol)
+	}
+
+	n := 1 + r.Intn(4)
+	for i := 0; i < n; i++ {
+		name := fmt.Sprintf("s%d", i)
+		if i == n-1 && i > 0 && r.Intn(4) == 0 {
+			name = trapState
+		}
+		fa.States = append(fa.States, name)
+		if r.Intn(3) == 0 {
+			fa.Acceptance = append(fa.Acceptance, name)
+		}
+	}
+	fa.Initial = fa.States[0]
+
+	for range fa.States {
+		row := make([]any, len(fa.Alphabet))
+		for c := range row {
+			switch r.Intn(4) {
+			case 0:
+				row[c] = noState
+			case 1:
+				row[c] = []any{fa.States[r.Intn(n)], fa.States[r.Intn(n)]}
+			default:
+				row[c] = fa.States[r.Intn(n)]
+			}
+		}
+		fa.Transitions = append(fa.Transitions, row)
+	}
+	return fa
+}
+
+// words lists every word over the symbols of alphabet, epsilon excluded, of
+// length at most max.
+func words(alphabet []string, max int) [][]string {
+	if max < 0 {
+		return nil
+	}
+	var symbols []string
+	for _, symbol := range alphabet {
+		if symbol != epsilonSymbol {
+			symbols = append(symbols, symbol)
+		}
+	}
+	result := [][]string{{}}
+	for start := 0; ; {
+		end := len(result)
+		for _, word := range result[start:end] {
+			if len(word) == max {
+				return result
+			}
+			for _, symbol := range symbols {
+				result = append(result, append(slices.Clone(word), symbol))
+			}
+		}
+		start = end
+	}
+}
+
+// mustCompile fails the test unless fa is well formed, in particular free of
+// duplicate state names, so that it can be fed to any other operation.
+func mustCompile(t *testing.T, fa *FA) *automaton {
+	t.Helper()
+	a, err := compile(fa)
+	if err != nil {
+		t.Fatalf("result does not compile: %v\n%+v", err, fa)
+	}
+	return a
+}
+
+// sameLanguage checks that got accepts exactly the words of length at most 4
+// over alphabet for which want returns true.
+func sameLanguage(t *testing.T, got *FA, alphabet []string, want func([]string) bool) {
+	t.Helper()
+	mustCompile(t, got)
+	for _, word := range words(alphabet, 4) {
+		if simulate(got, word) != want(word) {
+			t.Fatalf("%q: got %v, want %v\n%+v", strings.Join(word, ""), !want(word), want(word), got)
+		}
+	}
+}
+
+func TestCompileRoundTrip(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 500; i++ {
+		fa := randomFA(r, []string{"a", "b"}, true)
+		result := mustCompile(t, fa).toFA()
+
+		if !slices.Equal(result.States, fa.States) || result.Initial != fa.Initial {
+			t.Fatalf("states changed: %+v became %+v", fa, result)
+		}
+		for _, state := range fa.States {
+			if slices.Contains(fa.Acceptance, state) != slices.Contains(result.Acceptance, state) {
+				t.Fatalf("acceptance of %q changed: %+v became %+v", state, fa, result)
+			}
+		}
+		for q := range fa.States {
+			for c, symbol := range fa.Alphabet {
+				want := cellTargets(fa.Transitions[q][c])
+				slices.Sort(want)
+				want = slices.Compact(want)
+				var got []string
+				if column := slices.Index(result.Alphabet, symbol); column >= 0 {
+					got = cellTargets(result.Transitions[q][column])
+					slices.Sort(got)
+				}
+				if !slices.Equal(got, want) {
+					t.Fatalf("transitions of %q on %q changed: %+v became %+v", fa.States[q], symbol, fa, result)
+				}
+			}
+		}
+
+		again := mustCompile(t, result).toFA()
+		if !reflect.DeepEqual(again, result) {
+			t.Fatalf("round trip is not stable: %+v became %+v", result, again)
+		}
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	tests := map[string]*FA{
+		"duplicate symbol": {Alphabet: []string{"a", "a"}, States: []string{"p"}, Initial: "p",
+			Transitions: [][]any{{"p", "p"}}},
+		"duplicate state": {Alphabet: []string{"a"}, States: []string{"p", "p"}, Initial: "p",
+			Transitions: [][]any{{"p"}, {"p"}}},
+		"unknown initial": {Alphabet: []string{"a"}, States: []string{"p"}, Initial: "q",
+			Transitions: [][]any{{"p"}}},
+		"unknown acceptance": {Alphabet: []string{"a"}, States: []string{"p"}, Initial: "p", Acceptance: []string{"q"},
+			Transitions: [][]any{{"p"}}},
+		"unknown target": {Alphabet: []string{"a"}, States: []string{"p"}, Initial: "p",
+			Transitions: [][]any{{[]any{"p", "q"}}}},
+	}
+	for name, fa := range tests {
+		if _, err := compile(fa); err == nil {
+			t.Errorf("%s: compiled without error", name)
+		}
+	}
+}
+
+func TestRunString(t *testing.T) {
+	r := rand.New(rand.NewSource(2))
+	for i := 0; i < 500; i++ {
+		fa := randomFA(r, []string{"a", "b"}, true)
+		// c is never in the alphabet and must reject
+		for _, word := range words([]string{"a", "b", "c"}, 4) {
+			accepted, _, err := RunString(fa, strings.Join(word, ""))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if accepted != simulate(fa, word) {
+				t.Fatalf("%q: got %v\n%+v", strings.Join(word, ""), accepted, fa)
+			}
+		}
+	}
+}
+
+func TestNFAToDFA(t *testing.T) {
+	r := rand.New(rand.NewSource(3))
+	for i := 0; i < 500; i++ {
+		nfa := randomFA(r, []string{"a", "b"}, true)
+		dfa, err := NFAToDFA(nfa)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if a := mustCompile(t, dfa); !a.isDeterministic() || !a.isComplete() {
+			t.Fatalf("result is not a complete DFA: %+v", dfa)
+		}
+		sameLanguage(t, dfa, nfa.Alphabet, func(word []string) bool { return simulate(nfa, word) })
+	}
+}
+
+func TestPerformBoolean(t *testing.T) {
+	r := rand.New(rand.NewSource(5))
+	alphabet := []string{"a", "b", "c"}
+	for i := 0; i < 1000; i++ {
+		fas := []*FA{randomFA(r, alphabet, true), randomFA(r, alphabet, true)}
+		if r.Intn(2) == 0 {
+			fas = append(fas, randomFA(r, alphabet, true))
+		}
+		for _, mode := range []BooleanMode{Union, Intersection, Difference, SymmetricDifference} {
+			accept, err := mode.acceptance()
+			if err != nil {
+				t.Fatal(err)
+			}
+			result, err := PerformBoolean(fas, mode)
+			if err != nil {
+				t.Fatal(err)
+			}
+			sameLanguage(t, result, alphabet, func(word []string) bool {
+				flags := make([]bool, len(fas))
+				for j, fa := range fas {
+					flags[j] = simulate(fa, word)
+				}
+				return accept(flags)
+			})
+		}
+	}
+}
+
+func TestPerformBooleanExpression(t *testing.T) {
+	r := rand.New(rand.NewSource(6))
+	alphabet := []string{"a", "b", "c"}
+	for i := 0; i < 500; i++ {
+		fas := []*FA{randomFA(r, alphabet, true), randomFA(r, alphabet, true), randomFA(r, alphabet, true)}
+		result, err := PerformBooleanExpression(fas, "A ∩ ¬B ∪ C")
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Complement is relative to the shared alphabet, i.e. the union of the
+		// operands' alphabets
+		var shared []string
+		for _, fa := range fas {
+			for _, symbol := range fa.Alphabet {
+				if symbol != epsilonSymbol && !slices.Contains(shared, symbol) {
+					shared = append(shared, symbol)
+				}
+			}
+		}
+		sameLanguage(t, result, shared, func(word []string) bool {
+			return simulate(fas[0], word) && !simulate(fas[1], word) || simulate(fas[2], word)
+		})
+	}
+}
+
+// Errors quote the operator as written, not its canonical form
+func TestPerformBooleanExpressionError(t *testing.T) {
+	a := &FA{Alphabet: []string{"a"}, States: []string{"p"}, Initial: "p", Acceptance: []string{"p"},
+		Transitions: [][]any{{"p"}}}
+	for expression, want := range map[string]string{
+		"A--A":  `unexpected character '-' at position 2`,
+		"A ! ":  `unexpected character '!' at position 2`,
+		"A & |": `unexpected character '|' at position 4`,
+	} {
+		_, err := PerformBooleanExpression([]*FA{a}, expression)
+		if err == nil || err.Error() != want {
+			t.Errorf("%s: got error %v, want %s", expression, err, want)
+		}
+	}
+}
+
+// A partial DFA aligned to a larger alphabet gets a dead state, which must not
+// share its name with the sink of the product
+func TestPerformBooleanPartialOperand(t *testing.T) {
+	a := &FA{Alphabet: []string{"a"}, States: []string{"p"}, Initial: "p", Acceptance: []string{"p"},
+		Transitions: [][]any{{noState}}}
+	b := &FA{Alphabet: []string{"a", "b"}, States: []string{"r"}, Initial: "r", Acceptance: []string{},
+		Transitions: [][]any{{"r", "r"}}}
+	result, err := PerformBoolean([]*FA{a, b}, Union)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dfa, err := NFAToDFA(result)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sameLanguage(t, dfa, []string{"a", "b"}, func(word []string) bool { return len(word) == 0 })
+}
+
+// permuteColumns returns fa with its alphabet, epsilon included, and the
+// matching transition columns in random order.
+func permuteColumns(r *rand.Rand, fa *FA) *FA {
+	order := r.Perm(len(fa.Alphabet))
+	permuted := *fa
+	permuted.Alphabet = make([]string, len(order))
+	permuted.Transitions = make([][]any, len(fa.Transitions))
+	for q, row := range fa.Transitions {
+		permuted.Transitions[q] = make([]any, len(order))
+		for c, from := range order {
+			permuted.Transitions[q][c] = row[from]
+		}
+	}
+	for c, from := range order {
+		permuted.Alphabet[c] = fa.Alphabet[from]
+	}
+	return &permuted
+}
+
+// Operands listing the same symbols in a different order are aligned by name
+func TestPermutedAlphabets(t *testing.T) {
+	ab := &FA{Alphabet: []string{"a", "b"}, States: []string{"p", "q"}, Initial: "p", Acceptance: []string{"q"},
+		Transitions: [][]any{{"q", noState}, {noState, noState}}}
+	ba := &FA{Alphabet: []string{"b", "a"}, States: []string{"r", "s"}, Initial: "r", Acceptance: []string{"s"},
+		Transitions: [][]any{{"s", noState}, {noState, noState}}}
+	union, err := PerformBoolean([]*FA{ab, ba}, Union)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sameLanguage(t, union, []string{"a", "b"}, func(word []string) bool { return len(word) == 1 })
+	intersection, _, err := NPerformBoolean([]*FA{ab, ba}, Intersection)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sameLanguage(t, intersection, []string{"a", "b"}, func(word []string) bool { return false })
+	concatenation, _, err := Concatenation([]*FA{ab, ba})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sameLanguage(t, concatenation, []string{"a", "b"}, func(word []string) bool {
+		return slices.Equal(word, []string{"a", "b"})
+	})
+
+	r := rand.New(rand.NewSource(26))
+	alphabet := []string{"a", "b", "c"}
+	for i := 0; i < 300; i++ {
+		fas := []*FA{randomFA(r, alphabet, true), randomFA(r, alphabet, true)}
+		permuted := []*FA{fas[0], permuteColumns(r, fas[1])}
+		for _, mode := range []BooleanMode{Union, Intersection, Difference, SymmetricDifference} {
+			accept, err := mode.acceptance()
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := func(word []string) bool {
+				return accept([]bool{simulate(fas[0], word), simulate(fas[1], word)})
+			}
+			result, err := PerformBoolean(permuted, mode)
+			if err != nil {
+				t.Fatal(err)
+			}
+			sameLanguage(t, result, alphabet, want)
+			if mode == Union || mode == Intersection {
+				result, provenance, err := NPerformBoolean(permuted, mode)
+				if err != nil {
+					t.Fatal(err)
+				}
+				checkProvenance(t, result, permuted, provenance)
+				sameLanguage(t, result, alphabet, want)
+			}
+		}
+
+		result, provenance, err := Concatenation(permuted)
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkProvenance(t, result, permuted, provenance)
+		sameLanguage(t, result, alphabet, func(word []string) bool {
+			for split := 0; split <= len(word); split++ {
+				if simulate(fas[0], word[:split]) && simulate(fas[1], word[split:]) {
+					return true
+				}
+			}
+			return false
+		})
+	}
+}
+
+// checkProvenance fails unless every entry of provenance names a state of
+// the operand it claims and a state of result.
+func checkProvenance(t *testing.T, result *FA, fas []*FA, provenance Provenance) {
+	t.Helper()
+	for state, origin := range provenance {
+		if !slices.Contains(result.States, state) {
+			t.Fatalf("provenance of unknown state %q", state)
+		}
+		if origin.Operand < 0 || origin.Operand >= len(fas) || !slices.Contains(fas[origin.Operand].States, origin.State) {
+			t.Fatalf("state %q comes from %+v, which does not exist", state, origin)
+		}
+	}
+}
+
+func TestNPerformBoolean(t *testing.T) {
+	r := rand.New(rand.NewSource(7))
+	alphabet := []string{"a", "b", "c"}
+	for i := 0; i < 500; i++ {
+		fas := []*FA{randomFA(r, alphabet, true), randomFA(r, alphabet, true)}
+
+		union, provenance, err := NPerformBoolean(fas, Union)
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkProvenance(t, union, fas, provenance)
+		sameLanguage(t, union, alphabet, func(word []string) bool {
+			return simulate(fas[0], word) || simulate(fas[1], word)
+		})
+
+		intersection, _, err := NPerformBoolean(fas, Intersection)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sameLanguage(t, intersection, alphabet, func(word []string) bool {
+			return simulate(fas[0], word) && simulate(fas[1], word)
+		})
+	}
+}
+
+func TestConcatenation(t *testing.T) {
+	r := rand.New(rand.NewSource(8))
+	alphabet := []string{"a", "b", "c"}
+	for i := 0; i < 500; i++ {
+		fas := []*FA{randomFA(r, alphabet, true), randomFA(r, alphabet, true)}
+		result, provenance, err := Concatenation(fas)
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkProvenance(t, result, fas, provenance)
+		sameLanguage(t, result, alphabet, func(word []string) bool {
+			for split := 0; split <= len(word); split++ {
+				if simulate(fas[0], word[:split]) && simulate(fas[1], word[split:]) {
+					return true
+				}
+			}
+			return false
+		})
+
+		single, provenance, err := Concatenation(fas[:1])
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkProvenance(t, single, fas[:1], provenance)
+		sameLanguage(t, single, alphabet, func(word []string) bool { return simulate(fas[0], word) })
+	}
+}
+
+// split turns a string of one-character symbols into a word.
+func split(s string) []string {
+	word := []string{}
+	for _, r := range s {
+		word = append(word, string(r))
+	}
+	return word
+}
+
+// checkWitness checks that witness is a shortest word for which want holds,
+// or, when it is nil, that want holds for no word of length at most 5.
+func checkWitness(t *testing.T, witness *string, alphabet []string, want func([]string) bool) {
+	t.Helper()
+	max := 5
+	if witness != nil {
+		word := split(*witness)
+		if !want(word) {
+			t.Fatalf("%q is no witness", *witness)
+		}
+		max = len(word) - 1
+	}
+	for _, word := range words(alphabet, max) {
+		if want(word) {
+			t.Fatalf("missed witness %q, got %v", strings.Join(word, ""), witness)
+		}
+	}
+}
+
+func TestEquivalent(t *testing.T) {
+	r := rand.New(rand.NewSource(9))
+	alphabet := []string{"a", "b", "c"}
+	for i := 0; i < 1000; i++ {
+		a, b := randomFA(r, alphabet, true), randomFA(r, alphabet, true)
+		if i%4 == 0 {
+			// Make sure equivalent pairs come up
+			var err error
+			if b, err = NFAToDFA(a); err != nil {
+				t.Fatal(err)
+			}
+		}
+		result, err := Equivalent(a, b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.Equivalent != (result.Counterexample == nil) {
+			t.Fatalf("equivalent is %v with counterexample %v", result.Equivalent, result.Counterexample)
+		}
+		checkWitness(t, result.Counterexample, alphabet, func(word []string) bool {
+			return simulate(a, word) != simulate(b, word)
+		})
+		if result.Counterexample != nil {
+			acceptedBy := 2
+			if simulate(a, split(*result.Counterexample)) {
+				acceptedBy = 1
+			}
+			if result.AcceptedBy != acceptedBy {
+				t.Fatalf("%q accepted by %d, not %d", *result.Counterexample, acceptedBy, result.AcceptedBy)
+			}
+		}
+	}
+}
+
+func TestSubset(t *testing.T) {
+	r := rand.New(rand.NewSource(10))
+	alphabet := []string{"a", "b", "c"}
+	for i := 0; i < 1000; i++ {
+		a, b := randomFA(r, alphabet, true), randomFA(r, alphabet, true)
+		if i%4 == 0 {
+			// Make sure inclusions come up
+			var err error
+			if b, _, err = NPerformBoolean([]*FA{a, b}, Union); err != nil {
+				t.Fatal(err)
+			}
+		}
+		result, err := Subset(a, b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.Subset != (result.Witness == nil) {
+			t.Fatalf("subset is %v with witness %v", result.Subset, result.Witness)
+		}
+		checkWitness(t, result.Witness, alphabet, func(word []string) bool {
+			return simulate(a, word) && !simulate(b, word)
+		})
+	}
+}
+
+func TestDisjoint(t *testing.T) {
+	r := rand.New(rand.NewSource(11))
+	alphabet := []string{"a", "b", "c"}
+	for i := 0; i < 1000; i++ {
+		a, b := randomFA(r, alphabet, true), randomFA(r, alphabet, true)
+		result, err := Disjoint(a, b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.Disjoint != (result.Witness == nil) {
+			t.Fatalf("disjoint is %v with witness %v", result.Disjoint, result.Witness)
+		}
+		checkWitness(t, result.Witness, alphabet, func(word []string) bool {
+			return simulate(a, word) && simulate(b, word)
+		})
+	}
+}
+
+func TestAnalyze(t *testing.T) {
+	r := rand.New(rand.NewSource(12))
+	for tested := 0; tested < 300; {
+		fa := randomFA(r, []string{"a", "b"}, true)
+		dfa, err := NFAToDFA(fa)
+		if err != nil {
+			t.Fatal(err)
+		}
+		n := len(dfa.States)
+		if n > 6 {
+			continue
+		}
+		tested++
+
+		// A word accepted by a DFA with n states and at least n symbols long
+		// goes through a cycle, so words shorter than 2n decide everything:
+		// the language is empty or universal as soon as it is for the words
+		// shorter than n, and infinite if it has a word between n and 2n
+		empty, universal, finite := true, true, true
+		shortest, longest, count := -1, -1, int64(0)
+		for _, word := range words(fa.Alphabet, 2*n-1) {
+			accepted := simulate(fa, word)
+			if len(word) >= n {
+				finite = finite && !accepted
+				continue
+			}
+			universal = universal && accepted
+			if accepted {
+				empty = false
+				if shortest < 0 {
+					shortest = len(word)
+				}
+				longest = len(word)
+				count++
+			}
+		}
+
+		analysis, err := Analyze(fa)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if analysis.Empty != empty || analysis.Universal != universal || analysis.Finite != finite {
+			t.Fatalf("got %+v, want empty %v, universal %v, finite %v\n%+v", analysis, empty, universal, finite, fa)
+		}
+		if !empty && (analysis.ShortestLength == nil || *analysis.ShortestLength != shortest) {
+			t.Fatalf("got shortest length %v, want %d\n%+v", analysis.ShortestLength, shortest, fa)
+		}
+		if finite && !empty && (analysis.LongestLength == nil || *analysis.LongestLength != longest) {
+			t.Fatalf("got longest length %v, want %d\n%+v", analysis.LongestLength, longest, fa)
+		}
+		if finite && (analysis.Count == nil || analysis.Count.Int64() != count) {
+			t.Fatalf("got count %v, want %d\n%+v", analysis.Count, count, fa)
+		}
+	}
+}
+
+func TestEnumerate(t *testing.T) {
+	r := rand.New(rand.NewSource(13))
+	for i := 0; i < 500; i++ {
+		fa := randomFA(r, []string{"a", "b"}, true)
+		maxLen, limit := r.Intn(6), r.Intn(8)
+
+		// words lists words in shortlex order over the order of the alphabet
+		want := []string{}
+		counts := make([]int64, maxLen+1)
+		for _, word := range words(fa.Alphabet, maxLen) {
+			if simulate(fa, word) {
+				want = append(want, strings.Join(word, ""))
+				counts[len(word)]++
+			}
+		}
+		if limit > 0 && len(want) > limit {
+			want = want[:limit]
+		}
+
+		got, err := Enumerate(fa, maxLen, limit)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Equal(got, want) {
+			t.Fatalf("got %q, want %q\n%+v", got, want, fa)
+		}
+		gotCounts, err := CountByLength(fa, maxLen)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for length, count := range counts {
+			if gotCounts[length].Int64() != count {
+				t.Fatalf("got %v words by length, want %v\n%+v", gotCounts, counts, fa)
+			}
+		}
+	}
+}
+
+// The limit bounds the work of Enumerate, which only spells the words it returns
+func TestEnumerateLimit(t *testing.T) {
+	// (a∪b)^24, which has 2^24 words
+	fa := &FA{Alphabet: []string{"a", "b"}, Acceptance: []string{"s24"}}
+	for i := 0; i <= 24; i++ {
+		fa.States = append(fa.States, fmt.Sprintf("s%d", i))
+		next := fmt.Sprintf("s%d", i+1)
+		if i == 24 {
+			next = noState
+		}
+		fa.Transitions = append(fa.Transitions, []any{next, next})
+	}
+	fa.Initial = "s0"
+
+	got, err := Enumerate(fa, 30, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{strings.Repeat("a", 24), strings.Repeat("a", 23) + "b"}
+	if !slices.Equal(got, want) {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+
+	if _, err := Enumerate(fa, -1, 1); err == nil {
+		t.Error("enumerated words of negative length")
+	}
+	if _, err := CountByLength(fa, -2); err == nil {
+		t.Error("counted words of negative length")
+	}
+}
+
+func TestSampler(t *testing.T) {
+	r := rand.New(rand.NewSource(14))
+	for i := 0; i < 300; i++ {
+		fa := randomFA(r, []string{"a", "b"}, true)
+		length := r.Intn(6)
+		exists := map[bool]bool{}
+		for _, word := range words(fa.Alphabet, length) {
+			if len(word) == length {
+				exists[simulate(fa, word)] = true
+			}
+		}
+
+		sampler, err := NewSampler(fa, 42)
+		if err != nil {
+			t.Fatal(err)
+		}
+		again, err := NewSampler(fa, 42)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, accepted := range []bool{true, false} {
+			draw, redraw := sampler.Accepted, again.Accepted
+			if !accepted {
+				draw, redraw = sampler.Rejected, again.Rejected
+			}
+			drawn := draw(length, 5)
+			if !slices.Equal(drawn, redraw(length, 5)) {
+				t.Fatalf("same seed drew %q, then %q", drawn, redraw(length, 5))
+			}
+			if want := map[bool]int{true: 5}[exists[accepted]]; len(drawn) != want {
+				t.Fatalf("drew %d words, want %d\n%+v", len(drawn), want, fa)
+			}
+			for _, s := range drawn {
+				word := split(s)
+				if len(word) != length || simulate(fa, word) != accepted {
+					t.Fatalf("drew %q, want length %d and accepted %v\n%+v", s, length, accepted, fa)
+				}
+				for _, symbol := range word {
+					if !slices.Contains(fa.Alphabet, symbol) {
+						t.Fatalf("drew %q, not over the alphabet of %+v", s, fa)
+					}
+				}
+			}
+		}
+	}
+}
+
+// factorsInto reports whether word splits into one or more nonempty factors
+// accepted by fa.
+func factorsInto(fa *FA, word []string) bool {
+	splits := make([]bool, len(word)+1)
+	splits[0] = true
+	for end := 1; end <= len(word); end++ {
+		for start := 0; start < end && !splits[end]; start++ {
+			splits[end] = splits[start] && simulate(fa, word[start:end])
+		}
+	}
+	return len(word) > 0 && splits[len(word)]
+}
+
+func TestUnaryOperations(t *testing.T) {
+	operations := map[string]struct {
+		apply func(*FA) (*FA, error)
+		want  func(*FA, []string) bool
+	}{
+		"reverse": {Reverse, func(fa *FA, word []string) bool {
+			reversed := slices.Clone(word)
+			slices.Reverse(reversed)
+			return simulate(fa, reversed)
+		}},
+		"star": {Star, func(fa *FA, word []string) bool {
+			return len(word) == 0 || factorsInto(fa, word)
+		}},
+		"plus": {Plus, func(fa *FA, word []string) bool {
+			return simulate(fa, word) || factorsInto(fa, word)
+		}},
+		"optional": {Optional, func(fa *FA, word []string) bool {
+			return len(word) == 0 || simulate(fa, word)
+		}},
+	}
+	r := rand.New(rand.NewSource(15))
+	for i := 0; i < 500; i++ {
+		fa := randomFA(r, []string{"a", "b"}, true)
+		for name, operation := range operations {
+			result, err := operation.apply(fa)
+			if err != nil {
+				t.Fatalf("%s: %v", name, err)
+			}
+			sameLanguage(t, result, fa.Alphabet, func(word []string) bool { return operation.want(fa, word) })
+		}
+	}
+}
+
+// randomDFA returns a complete DFA with n states over alphabet.
+func randomDFA(r *rand.Rand, n int, alphabet []string) *FA {
+	fa := &FA{Alphabet: alphabet, Initial: "s0", Acceptance: []string{}}
+	for i := 0; i < n; i++ {
+		fa.States = append(fa.States, fmt.Sprintf("s%d", i))
+		if r.Intn(2) == 0 {
+			fa.Acceptance = append(fa.Acceptance, fa.States[i])
+		}
+	}
+	for range fa.States {
+		row := make([]any, len(alphabet))
+		for c := range row {
+			row[c] = fmt.Sprintf("s%d", r.Intn(n))
+		}
+		fa.Transitions = append(fa.Transitions, row)
+	}
+	return fa
+}
+
+var minimizationAlgorithms = []MinimizationAlgorithm{Hopcroft, Moore, Brzozowski}
+
+// A partial unreachable state does not make the reachable part, whose dead
+// state D is kept, count as partial
+func TestMinimizeUnreachablePartial(t *testing.T) {
+	fa := &FA{Alphabet: []string{"a", "b"}, States: []string{"P", "Q", "D", "U"}, Initial: "P",
+		Acceptance: []string{"Q"}, Transitions: [][]any{
+			{"Q", "D"}, {"D", "D"}, {"D", "D"}, {noState, "U"},
+		}}
+	for _, algorithm := range minimizationAlgorithms {
+		minimized, _, err := MinimizeDFAWith(fa, algorithm)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(minimized.States) != 3 || !mustCompile(t, minimized).isComplete() {
+			t.Errorf("%s: minimized to %+v, want the complete 3-state DFA", algorithm, minimized)
+		}
+	}
+}
+
+// The DFA for "the n-th symbol is a" has n+2 states, but its reversal
+// determinizes into 2^n subsets, which Brzozowski's algorithm refuses to build
+func TestMinimizeBrzozowskiLimit(t *testing.T) {
+	const n = 14
+	fa := &FA{Alphabet: []string{"a", "b"}, Initial: "s0", Acceptance: []string{"yes"}}
+	for i := 0; i < n; i++ {
+		fa.States = append(fa.States, fmt.Sprintf("s%d", i))
+		next := fmt.Sprintf("s%d", i+1)
+		if i == n-1 {
+			fa.Transitions = append(fa.Transitions, []any{"yes", "no"})
+		} else {
+			fa.Transitions = append(fa.Transitions, []any{next, next})
+		}
+	}
+	fa.States = append(fa.States, "yes", "no")
+	fa.Transitions = append(fa.Transitions, []any{"yes", "yes"}, []any{"no", "no"})
+
+	if 1<<n <= maxBrzozowskiStates {
+		t.Fatalf("2^%d subsets within the limit of %d", n, maxBrzozowskiStates)
+	}
+	if _, _, err := MinimizeDFAWith(fa, Brzozowski); err == nil {
+		t.Fatal("no error past the subset limit")
+	}
+	for _, algorithm := range []MinimizationAlgorithm{Hopcroft, Moore} {
+		minimized, _, err := MinimizeDFAWith(fa, algorithm)
+		if err != nil {
+			t.Fatalf("%s: %v", algorithm, err)
+		}
+		if len(minimized.States) != n+2 {
+			t.Fatalf("%s: %d states, want %d", algorithm, len(minimized.States), n+2)
+		}
+	}
+}
+
+func TestMinimize(t *testing.T) {
+	r := rand.New(rand.NewSource(16))
+	for i := 0; i < 1000; i++ {
+		fa := randomFA(r, []string{"a", "b"}, true)
+		var first *FA
+		for _, algorithm := range minimizationAlgorithms {
+			minimized, _, err := MinimizeDFAWith(fa, algorithm)
+			if err != nil {
+				t.Fatalf("%s: %v", algorithm, err)
+			}
+			if !mustCompile(t, minimized).isDeterministic() {
+				t.Fatalf("%s: result is not a DFA: %+v", algorithm, minimized)
+			}
+			sameLanguage(t, minimized, fa.Alphabet, func(word []string) bool { return simulate(fa, word) })
+			if first == nil {
+				first = minimized
+			} else if !reflect.DeepEqual(minimized, first) {
+				t.Fatalf("%s gave %+v, %s %+v\n%+v", algorithm, minimized, minimizationAlgorithms[0], first, fa)
+			}
+		}
+	}
+
+	single := &FA{Alphabet: []string{"a"}, States: []string{"p"}, Initial: "p", Transitions: [][]any{{"p"}}}
+	if _, _, err := MinimizeDFAWith(single, "bogus"); err == nil {
+		t.Error("minimized with an unknown algorithm")
+	}
+}
+
+// Hopcroft's algorithm refines large partitions like Moore's algorithm
+func TestHopcroftLarge(t *testing.T) {
+	r := rand.New(rand.NewSource(17))
+	for i := 0; i < 20; i++ {
+		dfa := randomDFA(r, 100+r.Intn(200), []string{"a", "b", "c"})
+		hopcroft, _, err := MinimizeDFAWith(dfa, Hopcroft)
+		if err != nil {
+			t.Fatal(err)
+		}
+		moore, _, err := MinimizeDFAWith(dfa, Moore)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(hopcroft, moore) {
+			t.Fatalf("Hopcroft gave %d states, Moore %d", len(hopcroft.States), len(moore.States))
+		}
+		sameLanguage(t, hopcroft, dfa.Alphabet, func(word []string) bool { return simulate(dfa, word) })
+	}
+}
+
+func TestComplete(t *testing.T) {
+	r := rand.New(rand.NewSource(18))
+	for i := 0; i < 500; i++ {
+		fa := randomFA(r, []string{"a", "b"}, true)
+		complete, err := Complete(fa)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !mustCompile(t, complete).isComplete() {
+			t.Fatalf("result is not complete: %+v", complete)
+		}
+		sameLanguage(t, complete, fa.Alphabet, func(word []string) bool { return simulate(fa, word) })
+	}
+}
+
+func TestTrim(t *testing.T) {
+	r := rand.New(rand.NewSource(19))
+	for i := 0; i < 500; i++ {
+		fa := randomFA(r, []string{"a", "b"}, true)
+		trimmed, err := Trim(fa)
+		if err != nil {
+			t.Fatal(err)
+		}
+		a := mustCompile(t, trimmed)
+		for q, useful := range a.useful() {
+			if !useful && q != a.initial {
+				t.Fatalf("state %q is useless: %+v", a.states[q], trimmed)
+			}
+		}
+		sameLanguage(t, trimmed, fa.Alphabet, func(word []string) bool { return simulate(fa, word) })
+	}
+}
+
+func TestComplement(t *testing.T) {
+	r := rand.New(rand.NewSource(4))
+	for i := 0; i < 500; i++ {
+		fa := randomFA(r, []string{"a", "b"}, true)
+		complement, preprocessing, err := Complement(fa)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if a := mustCompile(t, complement); !a.isDeterministic() || !a.isComplete() {
+			t.Fatalf("result is not a complete DFA: %+v", complement)
+		}
+		input := mustCompile(t, fa)
+		if preprocessing.Determinized == input.isDeterministic() {
+			t.Fatalf("determinized is %v for %+v", preprocessing.Determinized, fa)
+		}
+		if !preprocessing.Determinized && preprocessing.Completed == input.isComplete() {
+			t.Fatalf("completed is %v for %+v", preprocessing.Completed, fa)
+		}
+		sameLanguage(t, complement, fa.Alphabet, func(word []string) bool { return !simulate(fa, word) })
+	}
+}
+
+func TestRemoveEpsilon(t *testing.T) {
+	r := rand.New(rand.NewSource(20))
+	for i := 0; i < 500; i++ {
+		fa := randomFA(r, []string{"a", "b"}, true)
+		result, err := RemoveEpsilon(fa)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if slices.Contains(result.Alphabet, epsilonSymbol) {
+			t.Fatalf("epsilon transitions left: %+v", result)
+		}
+		if !slices.Equal(result.States, fa.States) {
+			t.Fatalf("states changed from %v to %v", fa.States, result.States)
+		}
+		sameLanguage(t, result, fa.Alphabet, func(word []string) bool { return simulate(fa, word) })
+	}
+}
+
+// regexLanguage checks that the regular expression accepts the language of fa.
+func regexLanguage(t *testing.T, regex *Regex, fa *FA) {
+	t.Helper()
+	nfa, err := regex.ToNFA(Thompson)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, word := range words(fa.Alphabet, 4) {
+		if simulate(nfa, word) != simulate(fa, word) {
+			t.Fatalf("%s differs from %+v on %q", regex, fa, strings.Join(word, ""))
+		}
+	}
+}
+
+func TestEliminationOrders(t *testing.T) {
+	r := rand.New(rand.NewSource(21))
+	for i := 0; i < 300; i++ {
+		fa := randomFA(r, []string{"a", "b"}, true)
+		custom := slices.Clone(fa.States)
+		r.Shuffle(len(custom), func(i, j int) { custom[i], custom[j] = custom[j], custom[i] })
+		for _, order := range []EliminationOrder{ArrayOrder, FewestEdges, LabelWeight, CustomOrder} {
+			regex, err := RegexFromFAWith(fa, order, custom)
+			if err != nil {
+				t.Fatalf("%s: %v", order, err)
+			}
+			regexLanguage(t, regex, fa)
+		}
+	}
+}
+
+func TestEliminationTrace(t *testing.T) {
+	r := rand.New(rand.NewSource(22))
+	for i := 0; i < 300; i++ {
+		fa := randomFA(r, []string{"a", "b"}, true)
+		for _, order := range []EliminationOrder{ArrayOrder, FewestEdges, LabelWeight} {
+			regex, err := RegexFromFAWith(fa, order, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			traced, steps, err := EliminationTrace(fa, order, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if traced.key() != regex.key() {
+				t.Fatalf("%s: traced %s, want %s", order, traced, regex)
+			}
+			// The initial generalized NFA, then one per eliminated state
+			if len(steps) != len(fa.States)+1 {
+				t.Fatalf("%s: %d steps for %d states", order, len(steps), len(fa.States))
+			}
+			for _, step := range steps {
+				mustCompile(t, step.GNFA)
+			}
+		}
+	}
+}
+
+// Edge labels that print alike but differ, like the symbol ab and the
+// concatenation a·b left by eliminating x, stay apart in the traced GNFA
+func TestEliminationTraceLabels(t *testing.T) {
+	fa := &FA{Alphabet: []string{"ab", "a", "b"}, States: []string{"p", "m", "x", "f"}, Initial: "p",
+		Acceptance: []string{"f"}, Transitions: [][]any{
+			{"m", noState, noState},
+			{noState, "x", noState},
+			{noState, noState, "f"},
+			{noState, noState, noState},
+		}}
+	_, steps, err := EliminationTrace(fa, CustomOrder, []string{"x", "m", "p", "f"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	gnfa := mustCompile(t, steps[1].GNFA)
+	label := func(from, to string) string {
+		q, p := slices.Index(gnfa.states, from), slices.Index(gnfa.states, to)
+		for symbol, targets := range gnfa.delta[q] {
+			if slices.Contains(targets, p) {
+				return gnfa.alphabet[symbol]
+			}
+		}
+		t.Fatalf("no edge from %s to %s", from, to)
+		return ""
+	}
+	if ab, aThenB := label("p", "m"), label("m", "f"); ab == aThenB {
+		t.Errorf("ab and a·b share label %q", ab)
+	}
+}
+
+func TestNFAToDFATrace(t *testing.T) {
+	r := rand.New(rand.NewSource(23))
+	for i := 0; i < 500; i++ {
+		nfa := randomFA(r, []string{"a", "b"}, true)
+		dfa, err := NFAToDFA(nfa)
+		if err != nil {
+			t.Fatal(err)
+		}
+		traced, trace, err := NFAToDFATrace(nfa)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(traced, dfa) {
+			t.Fatalf("traced %+v, want %+v", traced, dfa)
+		}
+		if len(trace.Steps) != len(dfa.States) || len(trace.Subsets) != len(dfa.States) {
+			t.Fatalf("%d steps and %d subsets for %d states", len(trace.Steps), len(trace.Subsets), len(dfa.States))
+		}
+		for _, state := range dfa.States {
+			if _, ok := trace.Subsets[state]; !ok {
+				t.Fatalf("no subset for %q", state)
+			}
+		}
+	}
+}
+
+// Worked subset constructions: the textbook NFA for (a∪b)*abb, and one for
+// ab? whose epsilon closures and missing moves lead to the trap state
+func TestNFAToDFATraceWorked(t *testing.T) {
+	move := func(symbol string, move, closure []string, target string, isNew bool) SubsetMove {
+		return SubsetMove{Symbol: symbol, Move: move, Closure: closure, Target: target, New: isNew}
+	}
+	tests := []struct {
+		nfa  *FA
+		want *SubsetTrace
+	}{
+		{
+			&FA{Alphabet: []string{"a", "b"}, States: []string{"0", "1", "2", "3"}, Initial: "0",
+				Acceptance: []string{"3"}, Transitions: [][]any{
+					{[]any{"0", "1"}, "0"},
+					{noState, "2"},
+					{noState, "3"},
+					{noState, noState},
+				}},
+			&SubsetTrace{Start: "0", Closure: []string{"0"}, Steps: []SubsetStep{
+				{"q0", []string{"0"}, []SubsetMove{
+					move("a", []string{"0", "1"}, []string{"0", "1"}, "q1", true),
+					move("b", []string{"0"}, []string{"0"}, "q0", false),
+				}},
+				{"q1", []string{"0", "1"}, []SubsetMove{
+					move("a", []string{"0", "1"}, []string{"0", "1"}, "q1", false),
+					move("b", []string{"0", "2"}, []string{"0", "2"}, "q2", true),
+				}},
+				{"q2", []string{"0", "2"}, []SubsetMove{
+					move("a", []string{"0", "1"}, []string{"0", "1"}, "q1", false),
+					move("b", []string{"0", "3"}, []string{"0", "3"}, "q3", true),
+				}},
+				{"q3", []string{"0", "3"}, []SubsetMove{
+					move("a", []string{"0", "1"}, []string{"0", "1"}, "q1", false),
+					move("b", []string{"0"}, []string{"0"}, "q0", false),
+				}},
+			}, Subsets: map[string][]string{
+				"q0": {"0"}, "q1": {"0", "1"}, "q2": {"0", "2"}, "q3": {"0", "3"},
+			}},
+		},
+		{
+			&FA{Alphabet: []string{"a", "b", epsilonSymbol}, States: []string{"p", "q", "r"}, Initial: "p",
+				Acceptance: []string{"r"}, Transitions: [][]any{
+					{"q", noState, noState},
+					{noState, "r", "r"},
+					{noState, noState, noState},
+				}},
+			&SubsetTrace{Start: "p", Closure: []string{"p"}, Steps: []SubsetStep{
+				{"q0", []string{"p"}, []SubsetMove{
+					move("a", []string{"q"}, []string{"q", "r"}, "q1", true),
+					move("b", []string{}, []string{}, trapState, true),
+				}},
+				{"q1", []string{"q", "r"}, []SubsetMove{
+					move("a", []string{}, []string{}, trapState, false),
+					move("b", []string{"r"}, []string{"r"}, "q2", true),
+				}},
+				{"q2", []string{"r"}, []SubsetMove{
+					move("a", []string{}, []string{}, trapState, false),
+					move("b", []string{}, []string{}, trapState, false),
+				}},
+				{trapState, []string{}, []SubsetMove{
+					move("a", []string{}, []string{}, trapState, false),
+					move("b", []string{}, []string{}, trapState, false),
+				}},
+			}, Subsets: map[string][]string{
+				"q0": {"p"}, "q1": {"q", "r"}, "q2": {"r"}, trapState: {},
+			}},
+		},
+	}
+	for _, test := range tests {
+		_, trace, err := NFAToDFATrace(test.nfa)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(trace, test.want) {
+			t.Errorf("traced %+v, want %+v", trace, test.want)
+		}
+	}
+}
+
+func TestMinimizationTrace(t *testing.T) {
+	r := rand.New(rand.NewSource(24))
+	for i := 0; i < 300; i++ {
+		fa := randomFA(r, []string{"a", "b"}, true)
+		for _, algorithm := range minimizationAlgorithms {
+			minimized, _, err := MinimizeDFAWith(fa, algorithm)
+			if err != nil {
+				t.Fatal(err)
+			}
+			traced, _, trace, err := MinimizeDFATrace(fa, algorithm)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(traced, minimized) {
+				t.Fatalf("%s: traced %+v, want %+v", algorithm, traced, minimized)
+			}
+			for _, state := range minimized.States {
+				if len(trace.Blocks[state]) == 0 {
+					t.Fatalf("%s: no block for %q", algorithm, state)
+				}
+			}
+		}
+	}
+}
+
+// Worked minimizations: the textbook DFA for (a∪b)*abb, where A and C are
+// equivalent, with an unreachable state F; and a partial DFA whose state Z
+// is merged with the sink into the dead state
+func TestMinimizationTraceWorked(t *testing.T) {
+	abb := &FA{Alphabet: []string{"a", "b"}, States: []string{"A", "B", "C", "D", "E", "F"}, Initial: "A",
+		Acceptance: []string{"E"}, Transitions: [][]any{
+			{"B", "C"}, {"B", "D"}, {"B", "C"}, {"B", "E"}, {"B", "C"}, {"F", "A"},
+		}}
+	abbBlocks := map[string][]string{"q0": {"A", "C"}, "q1": {"B"}, "q2": {"D"}, "q3": {"E"}}
+	abbPartition := [][]string{{"A", "B", "C", "D"}, {"E"}}
+	partial := &FA{Alphabet: []string{"a", "b"}, States: []string{"P", "Q", "Z"}, Initial: "P",
+		Acceptance: []string{"Q"}, Transitions: [][]any{
+			{"Q", "Z"}, {noState, noState}, {"Z", "Z"},
+		}}
+	partialBlocks := map[string][]string{"q0": {"P"}, "q1": {"Q"}}
+	partialPartition := [][]string{{"P", "Z", trapState}, {"Q"}}
+
+	tests := []struct {
+		fa        *FA
+		algorithm MinimizationAlgorithm
+		want      *MinimizationTrace
+	}{
+		{abb, Hopcroft, &MinimizationTrace{
+			Unreachable: []string{"F"},
+			Partition:   abbPartition,
+			Refinements: []Refinement{
+				{Splitter: []string{"E"}, Symbol: "b", Block: []string{"A", "B", "C", "D"},
+					Into: [][]string{{"D"}, {"A", "B", "C"}}},
+				{Splitter: []string{"D"}, Symbol: "b", Block: []string{"A", "B", "C"},
+					Into: [][]string{{"B"}, {"A", "C"}}},
+			},
+			Blocks: abbBlocks,
+		}},
+		{abb, Moore, &MinimizationTrace{
+			Unreachable: []string{"F"},
+			Partition:   abbPartition,
+			Refinements: []Refinement{
+				{Round: 1, Block: []string{"A", "B", "C", "D"}, Into: [][]string{{"A", "B", "C"}, {"D"}}},
+				{Round: 2, Block: []string{"A", "B", "C"}, Into: [][]string{{"A", "C"}, {"B"}}},
+			},
+			Blocks: abbBlocks,
+		}},
+		{abb, Brzozowski, &MinimizationTrace{
+			Unreachable: []string{"F"},
+			Partition:   [][]string{},
+			Refinements: []Refinement{},
+			Blocks:      abbBlocks,
+		}},
+		{partial, Hopcroft, &MinimizationTrace{
+			Unreachable: []string{},
+			Sink:        trapState,
+			Partition:   partialPartition,
+			Refinements: []Refinement{
+				{Splitter: []string{"Q"}, Symbol: "a", Block: []string{"P", "Z", trapState},
+					Into: [][]string{{"P"}, {"Z", trapState}}},
+			},
+			Blocks: partialBlocks,
+			Dead:   []string{"Z"},
+		}},
+		{partial, Moore, &MinimizationTrace{
+			Unreachable: []string{},
+			Sink:        trapState,
+			Partition:   partialPartition,
+			Refinements: []Refinement{
+				{Round: 1, Block: []string{"P", "Z", trapState}, Into: [][]string{{"P"}, {"Z", trapState}}},
+			},
+			Blocks: partialBlocks,
+			Dead:   []string{"Z"},
+		}},
+		{partial, Brzozowski, &MinimizationTrace{
+			Unreachable: []string{},
+			Partition:   [][]string{},
+			Refinements: []Refinement{},
+			Blocks:      partialBlocks,
+			Dead:        []string{"Z"},
+		}},
+	}
+	for _, test := range tests {
+		_, _, trace, err := MinimizeDFATrace(test.fa, test.algorithm)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(trace, test.want) {
+			t.Errorf("%s: traced %+v, want %+v", test.algorithm, trace, test.want)
+		}
+	}
+}
+
+// Refinements are only recorded for small DFAs, since they may grow
+// quadratically with the number of states
+func TestMinimizationTraceLarge(t *testing.T) {
+	r := rand.New(rand.NewSource(25))
+	dfa := randomDFA(r, maxTracedStates+1, []string{"a", "b"})
+	for _, algorithm := range []MinimizationAlgorithm{Hopcroft, Moore} {
+		_, _, trace, err := MinimizeDFATrace(dfa, algorithm)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !trace.RefinementsOmitted || len(trace.Refinements) > 0 {
+			t.Fatalf("%s: recorded %d refinements", algorithm, len(trace.Refinements))
+		}
+		if len(trace.Blocks) == 0 {
+			t.Fatalf("%s: recorded no blocks", algorithm)
+		}
+	}
+}
@@ -0,0 +1,577 @@
+package logic
+
+import (
+	"fmt"
+	"slices"
+)
+
+// MinimizationAlgorithm selects how MinimizeDFAWith merges equivalent states.
+type MinimizationAlgorithm string
+
+const (
+	Hopcroft   MinimizationAlgorithm = "hopcroft"   // partition refinement by splitters
+	Moore      MinimizationAlgorithm = "moore"      // round-based partition refinement
+	Brzozowski MinimizationAlgorithm = "brzozowski" // double reversal and determinization
+)
+
+// MinimizationStage records the number of states after a step of a minimization.
+type MinimizationStage struct {
+	Step   string `json:"step"`
+	States int    `json:"states"`
+}
+
+// MinimizationTrace records how a DFA was minimized. State names refer to the
+// input, or to its determinization (q0..qn) if it was nondeterministic.
+type MinimizationTrace struct {
+	Unreachable        []string            `json:"unreachable"`    // states removed as unreachable
+	Sink               string              `json:"sink,omitempty"` // state added to complete the DFA
+	Partition          [][]string          `json:"partition"`      // initial non-accepting/accepting blocks
+	Refinements        []Refinement        `json:"refinements"`
+	RefinementsOmitted bool                `json:"refinements_omitted,omitempty"` // refinements not recorded, see maxTracedStates
+	Blocks             map[string][]string `json:"blocks"`                        // minimized state to the states it merges
+	Dead               []string            `json:"dead,omitempty"`                // states merged into the removed dead state
+}
+
+// maxTracedStates bounds the DFAs whose refinements are recorded: every
+// refinement lists the whole block it splits, so that the trace may grow
+// quadratically with the number of states.
+const maxTracedStates = 1000
+
+// maxBrzozowskiStates bounds the DFAs built by Brzozowski's algorithm, whose
+// reversals may determinize into exponentially many subsets even when the
+// input and its minimization are small.
+const maxBrzozowskiStates = 10000
+
+// Refinement is the split of a block of the partition. Hopcroft's algorithm
+// splits it into the states with a transition on Symbol into Splitter and the
+// others; Moore's algorithm splits it in a Round by the blocks its states lead
+// to on every symbol.
+type Refinement struct {
+	Round    int        `json:"round,omitempty"`
+	Splitter []string   `json:"splitter,omitempty"`
+	Symbol   string     `json:"symbol,omitempty"`
+	Block    []string   `json:"block"`
+	Into     [][]string `json:"into"`
+}
+
+// MinimizeDFA minimizes a DFA using Hopcroft's algorithm
+func MinimizeDFA(dfa *FA) (*FA, error) {
+	minimized, _, err := MinimizeDFAWith(dfa, Hopcroft)
+	return minimized, err
+}
+
+// MinimizeDFAWith minimizes an FA with the given algorithm and reports the size
+// of the automaton after every step. Nondeterministic input is determinized
+// first. All algorithms yield the same automaton: the minimal DFA with states
+// numbered q0..qn in breadth-first order from the initial state, which is
+// complete unless the (determinized) input was not, in which case its dead
+// state is left out. Brzozowski's algorithm fails on inputs whose reversals
+// determinize into more than maxBrzozowskiStates states.
+func MinimizeDFAWith(fa *FA, algorithm MinimizationAlgorithm) (*FA, []MinimizationStage, error) {
+	return minimize(fa, algorithm, nil)
+}
+
+// MinimizeDFATrace minimizes an FA like MinimizeDFAWith and also records the
+// states removed or merged on the way and, for Hopcroft's and Moore's
+// algorithms, every refinement of the partition.
+func MinimizeDFATrace(fa *FA, algorithm MinimizationAlgorithm) (*FA, []MinimizationStage, *MinimizationTrace, error) {
+	trace := &MinimizationTrace{
+		Unreachable: []string{},
+		Partition:   [][]string{},
+		Refinements: []Refinement{},
+		Blocks:      make(map[string][]string),
+	}
+	minimized, stages, err := minimize(fa, algorithm, trace)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return minimized, stages, trace, nil
+}
+
+// minimize implements MinimizeDFAWith, filling in trace unless it is nil.
+func minimize(fa *FA, algorithm MinimizationAlgorithm, trace *MinimizationTrace) (*FA, []MinimizationStage, error) {
+	switch algorithm {
+	case Hopcroft, Moore, Brzozowski:
+	default:
+		return nil, nil, fmt.Errorf("unsupported minimization algorithm %q", algorithm)
+	}
+
+	a, err := compile(fa)
+	if err != nil {
+		return nil, nil, err
+	}
+	stages := []MinimizationStage{{"input", len(a.states)}}
+	if !a.isDeterministic() {
+		a, _ = determinize(a)
+		stages = append(stages, MinimizationStage{"determinized", len(a.states)})
+	}
+	dfa := a
+	// Only the reachable part decides whether the input has a dead state to
+	// leave out, since unreachable states are dropped anyway
+	reachable := a.restrict(a.reachable())
+	partial := !reachable.isComplete()
+
+	if trace != nil {
+		for q, reachable := range a.reachable() {
+			if !reachable {
+				trace.Unreachable = append(trace.Unreachable, a.states[q])
+			}
+		}
+	}
+
+	if trace != nil && len(a.states) > maxTracedStates {
+		trace.RefinementsOmitted = true
+	}
+
+	var minimized *automaton
+	switch algorithm {
+	case Hopcroft, Moore:
+		// First, remove inaccessible states
+		a = reachable
+		stages = append(stages, MinimizationStage{"reachable", len(a.states)})
+		if a.complete() {
+			stages = append(stages, MinimizationStage{"completed", len(a.states)})
+			if trace != nil {
+				trace.Sink = a.states[len(a.states)-1]
+			}
+		}
+		if algorithm == Hopcroft {
+			minimized = hopcroft(a, trace)
+		} else {
+			minimized = moore(a, trace)
+		}
+	case Brzozowski:
+		minimized = a
+		for _, round := range []string{"", " again"} {
+			// The reversal starts from all the former accepting states at
+			// once; a fresh initial state would otherwise survive in the
+			// first subset and keep it apart from its equivalents
+			reversed := minimized.reverse()
+			stages = append(stages, MinimizationStage{"reversed" + round, len(reversed.states)})
+			start := newStateSet(len(reversed.states))
+			for q := range minimized.states {
+				if minimized.accept[q] {
+					start.add(q)
+				}
+			}
+			minimized, _, err = determinizeFrom(reversed, start, maxBrzozowskiStates)
+			if err != nil {
+				return nil, nil, fmt.Errorf("brzozowski minimization: %v", err)
+			}
+			stages = append(stages, MinimizationStage{"determinized" + round, len(minimized.states)})
+		}
+	}
+
+	if partial {
+		minimized = minimized.trim()
+		stages = append(stages, MinimizationStage{"dead state removed", len(minimized.states)})
+	}
+	minimized = minimized.canonical()
+	stages = append(stages, MinimizationStage{"minimized", len(minimized.states)})
+
+	if trace != nil {
+		trace.recordBlocks(dfa, minimized)
+	}
+	return minimized.toFA(), stages, nil
+}
+
+// recordBlocks fills in the states of dfa that every state of its
+// minimization merges, found by running both automata side by side from their
+// initial states. States whose counterpart was trimmed away are dead.
+func (trace *MinimizationTrace) recordBlocks(dfa, minimized *automaton) {
+	image := make([]int, len(dfa.states))
+	for q := range image {
+		image[q] = -2
+	}
+	image[dfa.initial] = minimized.initial
+	queue := []int{dfa.initial}
+	for len(queue) > 0 {
+		q := queue[0]
+		queue = queue[1:]
+		for symbol := range dfa.alphabet {
+			next := dfa.next(q, symbol)
+			if next < 0 || image[next] != -2 {
+				continue
+			}
+			image[next] = -1
+			if p := image[q]; p >= 0 {
+				image[next] = minimized.next(p, symbol)
+			}
+			queue = append(queue, next)
+		}
+	}
+
+	for q, p := range image {
+		switch {
+		case p >= 0:
+			name := minimized.states[p]
+			trace.Blocks[name] = append(trace.Blocks[name], dfa.states[q])
+		case p == -1:
+			trace.Dead = append(trace.Dead, dfa.states[q])
+		}
+	}
+}
+
+// stateNames returns the names of the given states, in index order.
+func (a *automaton) stateNames(states []int) []string {
+	sorted := slices.Clone(states)
+	slices.Sort(sorted)
+	names := make([]string, len(sorted))
+	for i, q := range sorted {
+		names[i] = a.states[q]
+	}
+	return names
+}
+
+// hopcroft merges the equivalent states of a reachable, complete DFA in
+// O(n·k·log n) time for n states and k symbols. The partition starts as
+// accepting/non-accepting and is refined by (splitter, symbol) pairs taken
+// from a worklist: every block holding both predecessors and non-predecessors
+// of the splitter on the symbol is split in two. Once a block has served as a
+// splitter, only the smaller half of a later split needs to be queued.
+func hopcroft(a *automaton, trace *MinimizationTrace) *automaton {
+	n, k := len(a.states), len(a.alphabet)
+	inverse := a.inverse()
+	p := newPartition(n)
+	p.split(func(q int) bool { return a.accept[q] })
+	if trace != nil {
+		for b := 0; b < p.blocks(); b++ {
+			trace.Partition = append(trace.Partition, a.stateNames(p.members(b)))
+		}
+	}
+
+	// Work list of (block, symbol) splitters
+	type splitter struct{ block, symbol int }
+	var workList []splitter
+	pending := make([][]bool, n)
+	for b := range pending {
+		pending[b] = make([]bool, k)
+	}
+	enqueue := func(block, symbol int) {
+		if !pending[block][symbol] {
+			pending[block][symbol] = true
+			workList = append(workList, splitter{block, symbol})
+		}
+	}
+	smaller := 0
+	if p.blocks() == 2 && p.size(1) < p.size(0) {
+		smaller = 1
+	}
+	for symbol := range a.alphabet {
+		enqueue(smaller, symbol)
+	}
+
+	var members []int
+	for len(workList) > 0 {
+		s := workList[len(workList)-1]
+		workList = workList[:len(workList)-1]
+		pending[s.block][s.symbol] = false
+
+		// Mark the predecessors of the splitter, copying its members first
+		// since marking reorders them
+		members = append(members[:0], p.members(s.block)...)
+		for _, q := range members {
+			for _, r := range inverse[s.symbol][q] {
+				p.mark(r)
+			}
+		}
+
+		for _, split := range p.splitMarked() {
+			if trace != nil && !trace.RefinementsOmitted {
+				added, rest := p.members(split.added), p.members(split.block)
+				trace.Refinements = append(trace.Refinements, Refinement{
+					Splitter: a.stateNames(members),
+					Symbol:   a.alphabet[s.symbol],
+					Block:    a.stateNames(slices.Concat(added, rest)),
+					Into:     [][]string{a.stateNames(added), a.stateNames(rest)},
+				})
+			}
+			for symbol := range a.alphabet {
+				if pending[split.block][symbol] || p.size(split.added) < p.size(split.block) {
+					enqueue(split.added, symbol)
+				} else {
+					enqueue(split.block, symbol)
+				}
+			}
+		}
+	}
+
+	blocks := make([][]int, p.blocks())
+	for b := range blocks {
+		blocks[b] = p.members(b)
+	}
+	return quotient(a, blocks, p.blockOf)
+}
+
+// inverse returns, for every symbol and state, the states with a transition
+// into it on that symbol.
+func (a *automaton) inverse() [][][]int {
+	inverse := make([][][]int, len(a.alphabet))
+	for symbol := range a.alphabet {
+		inverse[symbol] = make([][]int, len(a.states))
+	}
+	for q, row := range a.delta {
+		for symbol, targets := range row {
+			for _, p := range targets {
+				inverse[symbol][p] = append(inverse[symbol][p], q)
+			}
+		}
+	}
+	return inverse
+}
+
+// partition is a refinable partition of the states 0..n-1. The members of
+// every block are stored contiguously in elements, with the marked ones first,
+// so that marking a state and splitting off the marked part of a block take
+// time proportional to the number of marked states.
+type partition struct {
+	elements []int // states grouped by block
+	location []int // index of every state in elements
+	blockOf  []int
+	first    []int // start of every block in elements
+	end      []int // end of every block in elements
+	marked   []int // number of marked states at the start of every block
+	touched  []int // blocks with marked states
+}
+
+// newPartition returns the partition of 0..n-1 into a single block.
+func newPartition(n int) *partition {
+	p := &partition{
+		elements: make([]int, n),
+		location: make([]int, n),
+		blockOf:  make([]int, n),
+		first:    []int{0},
+		end:      []int{n},
+		marked:   []int{0},
+	}
+	for q := range p.elements {
+		p.elements[q] = q
+		p.location[q] = q
+	}
+	return p
+}
+
+func (p *partition) blocks() int         { return len(p.first) }
+func (p *partition) size(b int) int      { return p.end[b] - p.first[b] }
+func (p *partition) members(b int) []int { return p.elements[p.first[b]:p.end[b]] }
+
+// mark moves q to the marked part of its block.
+func (p *partition) mark(q int) {
+	b := p.blockOf[q]
+	i := p.first[b] + p.marked[b]
+	if p.location[q] < i {
+		return
+	}
+	if p.marked[b] == 0 {
+		p.touched = append(p.touched, b)
+	}
+	other := p.elements[i]
+	p.elements[i], p.elements[p.location[q]] = q, other
+	p.location[other], p.location[q] = p.location[q], i
+	p.marked[b]++
+}
+
+// blockSplit records that block added was split off block.
+type blockSplit struct{ block, added int }
+
+// splitMarked splits the marked part off every block that is only partly
+// marked, clears all marks and returns the splits made.
+func (p *partition) splitMarked() []blockSplit {
+	var splits []blockSplit
+	for _, b := range p.touched {
+		marked := p.marked[b]
+		p.marked[b] = 0
+		if marked == p.size(b) {
+			continue
+		}
+		added := len(p.first)
+		p.first = append(p.first, p.first[b])
+		p.end = append(p.end, p.first[b]+marked)
+		p.marked = append(p.marked, 0)
+		p.first[b] += marked
+		for _, q := range p.members(added) {
+			p.blockOf[q] = added
+		}
+		splits = append(splits, blockSplit{b, added})
+	}
+	p.touched = p.touched[:0]
+	return splits
+}
+
+// split moves the states satisfying the predicate into a new block.
+func (p *partition) split(predicate func(int) bool) {
+	for q := range p.location {
+		if predicate(q) {
+			p.mark(q)
+		}
+	}
+	p.splitMarked()
+}
+
+// moore merges the equivalent states of a reachable, complete DFA by refining the
+// accepting/non-accepting partition in rounds: two states stay together only
+// if they were together and their successors on every symbol were too. It
+// stops at the first round that splits no block.
+func moore(a *automaton, trace *MinimizationTrace) *automaton {
+	n := len(a.states)
+	blockOf := make([]int, n)
+	for q := range a.states {
+		if a.accept[q] {
+			blockOf[q] = 1
+		}
+	}
+	if trace != nil {
+		trace.Partition = groupBlocks(a, blockOf)
+	}
+	count := -1
+
+	for round := 1; ; round++ {
+		// Renumber blocks by signature, in order of first occurrence
+		index := make(map[string]int)
+		next := make([]int, n)
+		signature := make([]int, len(a.alphabet)+1)
+		for q := range a.states {
+			signature[0] = blockOf[q]
+			for symbol := range a.alphabet {
+				signature[symbol+1] = -1
+				if p := a.next(q, symbol); p >= 0 {
+					signature[symbol+1] = blockOf[p]
+				}
+			}
+			key := tupleKey(signature)
+			b, ok := index[key]
+			if !ok {
+				b = len(index)
+				index[key] = b
+			}
+			next[q] = b
+		}
+		if trace != nil && !trace.RefinementsOmitted {
+			trace.recordRound(a, round, blockOf, next)
+		}
+		blockOf = next
+		if len(index) == count {
+			break
+		}
+		count = len(index)
+	}
+
+	blocks := make([][]int, count)
+	for q, b := range blockOf {
+		blocks[b] = append(blocks[b], q)
+	}
+	return quotient(a, blocks, blockOf)
+}
+
+// groupBlocks returns the names of the states in every block, blocks in order
+// of their first state.
+func groupBlocks(a *automaton, blockOf []int) [][]string {
+	index := make(map[int]int)
+	var blocks [][]string
+	for q, b := range blockOf {
+		i, ok := index[b]
+		if !ok {
+			i = len(blocks)
+			index[b] = i
+			blocks = append(blocks, nil)
+		}
+		blocks[i] = append(blocks[i], a.states[q])
+	}
+	return blocks
+}
+
+// recordRound records every block of a round of Moore's algorithm that was
+// split, given the block of every state before and after the round.
+func (trace *MinimizationTrace) recordRound(a *automaton, round int, before, after []int) {
+	type split struct {
+		members []int
+		part    map[int]int // block after the round to index in parts
+		parts   [][]int
+	}
+	var order []int
+	splits := make(map[int]*split)
+	for q := range a.states {
+		s, ok := splits[before[q]]
+		if !ok {
+			s = &split{part: make(map[int]int)}
+			splits[before[q]] = s
+			order = append(order, before[q])
+		}
+		s.members = append(s.members, q)
+		i, ok := s.part[after[q]]
+		if !ok {
+			i = len(s.parts)
+			s.part[after[q]] = i
+			s.parts = append(s.parts, nil)
+		}
+		s.parts[i] = append(s.parts[i], q)
+	}
+
+	for _, b := range order {
+		s := splits[b]
+		if len(s.parts) < 2 {
+			continue
+		}
+		into := make([][]string, len(s.parts))
+		for i, part := range s.parts {
+			into[i] = a.stateNames(part)
+		}
+		trace.Refinements = append(trace.Refinements, Refinement{
+			Round: round,
+			Block: a.stateNames(s.members),
+			Into:  into,
+		})
+	}
+}
+
+// quotient builds the DFA whose states q0..qn are the given blocks of
+// equivalent states, numbered in block order.
+func quotient(a *automaton, blocks [][]int, blockOf []int) *automaton {
+	result := newAutomaton(a.alphabet)
+	for i, block := range blocks {
+		result.addState(fmt.Sprintf("q%d", i), a.accept[block[0]])
+	}
+	for i, block := range blocks {
+		representative := block[0]
+		for symbol := range a.alphabet {
+			if p := a.next(representative, symbol); p >= 0 {
+				result.addTransition(i, symbol, blockOf[p])
+			}
+		}
+	}
+	result.initial = blockOf[a.initial]
+	return result
+}
+
+// canonical renames the reachable states of a DFA q0..qn in breadth-first
+// order from the initial state, following symbols in alphabet order, and drops
+// the unreachable ones. Isomorphic DFAs over the same alphabet become equal.
+func (a *automaton) canonical() *automaton {
+	order := []int{a.initial}
+	index := make([]int, len(a.states))
+	for q := range index {
+		index[q] = -1
+	}
+	index[a.initial] = 0
+	for i := 0; i < len(order); i++ {
+		for symbol := range a.alphabet {
+			if p := a.next(order[i], symbol); p >= 0 && index[p] < 0 {
+				index[p] = len(order)
+				order = append(order, p)
+			}
+		}
+	}
+
+	result := newAutomaton(a.alphabet)
+	for i, q := range order {
+		result.addState(fmt.Sprintf("q%d", i), a.accept[q])
+	}
+	for i, q := range order {
+		for symbol := range a.alphabet {
+			if p := a.next(q, symbol); p >= 0 {
+				result.addTransition(i, symbol, index[p])
+			}
+		}
+	}
+	return result
+}
@@ -3,82 +3,106 @@ package logic
 
 import (
 	"fmt"
-	"strings"
+	"unicode/utf8"
 )
 
-// FAToRegex converts a finite automaton to a regular expression using state elimination
+// FAToRegex converts a finite automaton to a simplified regular expression using state elimination
 func FAToRegex(fa *FA) (string, error) {
+	regex, err := RegexFromFA(fa)
+	if err != nil {
+		return "", err
+	}
+	return regex.Simplify().String(), nil
+}
+
+// RegexFromFA builds the syntax tree of a regular expression equivalent to an
+// FA using state elimination
+func RegexFromFA(fa *FA) (*Regex, error) {
+	return RegexFromFAWith(fa, ArrayOrder, nil)
+}
+
+// RegexFromFAWith builds the syntax tree of a regular expression equivalent
+// to an FA, eliminating states in the given order. The states named in custom
+// are eliminated first when the order is CustomOrder.
+func RegexFromFAWith(fa *FA, order EliminationOrder, custom []string) (*Regex, error) {
+	regex, _, err := stateElimination(fa, order, custom, false)
+	return regex, err
+}
+
+// EliminationTrace builds the regex like RegexFromFAWith and also returns the
+// generalized NFA before any elimination and after every one
+func EliminationTrace(fa *FA, order EliminationOrder, custom []string) (*Regex, []EliminationStep, error) {
+	return stateElimination(fa, order, custom, true)
+}
+
+// stateElimination runs the state elimination algorithm, recording every
+// intermediate generalized NFA if trace is set
+func stateElimination(fa *FA, order EliminationOrder, custom []string, trace bool) (*Regex, []EliminationStep, error) {
 	if len(fa.States) == 0 {
-		return "∅", nil
+		return &Regex{Op: RegexEmpty}, nil, nil
+	}
+
+	a, err := compile(fa)
+	if err != nil {
+		return nil, nil, err
+	}
+	next, err := a.eliminationOrder(order, custom)
+	if err != nil {
+		return nil, nil, err
 	}
 
 	// Create a copy of the FA with added start and end states
 	// This simplifies the state elimination algorithm
 
 	// Create transition matrix
-	n := len(fa.States) + 2 // +2 for new start and end states
-	stateNames := make([]string, n)
-	stateNames[0] = "START"
-	copy(stateNames[1:], fa.States)
-	stateNames[n-1] = "END"
+	n := len(a.states) + 2 // +2 for new start and end states
 
 	// Initialize regex matrix
-	regexMatrix := make([][]string, n)
+	regexMatrix := make([][]*Regex, n)
 	for i := range regexMatrix {
-		regexMatrix[i] = make([]string, n)
+		regexMatrix[i] = make([]*Regex, n)
 		for j := range regexMatrix[i] {
-			regexMatrix[i][j] = "∅"
+			regexMatrix[i][j] = &Regex{Op: RegexEmpty}
 		}
 	}
 
 	// Add epsilon transition from START to original initial state
-	initialIdx := getStateIndexInList(fa.States, fa.Initial) + 1
-	regexMatrix[0][initialIdx] = "ε"
+	regexMatrix[0][a.initial+1] = &Regex{Op: RegexEpsilon}
 
 	// Add epsilon transitions from acceptance states to END
-	for _, acceptState := range fa.Acceptance {
-		acceptIdx := getStateIndexInList(fa.States, acceptState) + 1
-		regexMatrix[acceptIdx][n-1] = "ε"
-	}
-
-	// Fill in original transitions
-	for i := range fa.States {
-		for j, symbol := range fa.Alphabet {
-			next := fa.Transitions[i][j]
-			stateIdx := i + 1 // +1 because of START state
-
-			switch v := next.(type) {
-			case string:
-				if v != "@v" {
-					nextIdx := getStateIndexInList(fa.States, v) + 1
-					if regexMatrix[stateIdx][nextIdx] == "∅" {
-						regexMatrix[stateIdx][nextIdx] = symbol
-					} else {
-						regexMatrix[stateIdx][nextIdx] = unionRegex(regexMatrix[stateIdx][nextIdx], symbol)
-					}
-				}
-			case []string:
-				for _, nextState := range v {
-					if nextState != "@v" {
-						nextIdx := getStateIndexInList(fa.States, nextState) + 1
-						if regexMatrix[stateIdx][nextIdx] == "∅" {
-							regexMatrix[stateIdx][nextIdx] = symbol
-						} else {
-							regexMatrix[stateIdx][nextIdx] = unionRegex(regexMatrix[stateIdx][nextIdx], symbol)
-						}
-					}
-				}
+	for q := range a.states {
+		if a.accept[q] {
+			regexMatrix[q+1][n-1] = &Regex{Op: RegexEpsilon}
+		}
+	}
+
+	// Fill in original transitions (+1 because of START state)
+	for q := range a.states {
+		for symbol, targets := range a.delta[q] {
+			for _, p := range targets {
+				regexMatrix[q+1][p+1] = unionRegex(regexMatrix[q+1][p+1], &Regex{Op: RegexSymbol, Symbol: a.alphabet[symbol]})
 			}
 		}
+		for _, p := range a.eps[q] {
+			regexMatrix[q+1][p+1] = unionRegex(regexMatrix[q+1][p+1], &Regex{Op: RegexEpsilon})
+		}
 	}
 
 	// State elimination algorithm
 	// Eliminate states 1 to n-2 (keep START=0 and END=n-1)
-	for k := 1; k < n-1; k++ {
-		// For each pair of states (i,j), update the transition
+	removed := make([]bool, n)
+	var steps []EliminationStep
+	if trace {
+		steps = append(steps, EliminationStep{GNFA: a.gnfa(regexMatrix, removed)})
+	}
+	for step := 1; step < n-1; step++ {
+		k := next(regexMatrix, removed)
+		removed[k] = true
+
+		// For each pair of remaining states (i,j), update the transition
 		for i := 0; i < n; i++ {
 			for j := 0; j < n; j++ {
-				if i == k || j == k {
+				if removed[i] || removed[j] {
 					continue
 				}
 
@@ -87,141 +111,135 @@ func FAToRegex(fa *FA) (string, error) {
 				rkk := regexMatrix[k][k]
 				rkj := regexMatrix[k][j]
 
-				if rik != "∅" && rkj != "∅" {
-					var newPart string
-					if rkk == "∅" {
-						newPart = concatenateRegex(rik, rkj)
-					} else {
-						kleenePart := kleeneStarRegex(rkk)
-						newPart = concatenateRegex(rik, concatenateRegex(kleenePart, rkj))
-					}
+				if rik.Op != RegexEmpty && rkj.Op != RegexEmpty {
+					newPart := concatenateRegex(rik, concatenateRegex(kleeneStarRegex(rkk), rkj))
 					regexMatrix[i][j] = unionRegex(regexMatrix[i][j], newPart)
 				}
 			}
 		}
+
+		if trace {
+			steps = append(steps, EliminationStep{Eliminated: a.states[k-1], GNFA: a.gnfa(regexMatrix, removed)})
+		}
 	}
 
 	// The final regex is the transition from START to END
-	result := regexMatrix[0][n-1]
-	if result == "∅" {
-		return "∅", nil
-	}
-	return result, nil
+	return regexMatrix[0][n-1], steps, nil
 }
 
 // RegexToNFA converts a regular expression to an NFA using Thompson's construction
 func RegexToNFA(regex string) (*FA, error) {
-	if regex == "" || regex == "∅" {
-		return createEmptyNFA(), nil
+	return RegexToNFAWith(regex, Thompson, nil)
+}
+
+// RegexToNFAWith converts a regular expression to an NFA with the given
+// construction. All constructions work on the same parsed expression, whose
+// character classes and wildcards are expanded against alphabet (see
+// ParseRegexOver). An empty regex stands for the empty language.
+func RegexToNFAWith(regex string, algorithm RegexAlgorithm, alphabet []string) (*FA, error) {
+	node, err := parseConversion(regex, alphabet)
+	if err != nil {
+		return nil, err
 	}
-	if regex == "ε" {
-		return createEpsilonNFA(), nil
+	return node.ToNFAOver(algorithm, alphabet)
+}
+
+// ToNFA converts the expression to an NFA with the given construction
+func (r *Regex) ToNFA(algorithm RegexAlgorithm) (*FA, error) {
+	return r.ToNFAOver(algorithm, nil)
+}
+
+// ToNFAOver converts the expression to an NFA with the given construction,
+// over alphabet followed by the symbols of the expression it lacks
+func (r *Regex) ToNFAOver(algorithm RegexAlgorithm, alphabet []string) (*FA, error) {
+	symbols := r.alphabetOver(alphabet)
+	switch algorithm {
+	case Thompson:
+		builder := &RegexParser{}
+		return builder.thompson(r).toFA(symbols), nil
+	case Glushkov:
+		return glushkov(r, symbols).toFA(), nil
+	case Antimirov:
+		return antimirov(r, symbols).toFA(), nil
+	default:
+		return nil, fmt.Errorf("unsupported regex construction %q", algorithm)
 	}
+}
+
+// ParseRegex parses a whole regular expression into its syntax tree. Union is
+// written ∪ or |, Kleene star * or ∗ and plus +; ε or \e and ∅ stand for the
+// empty string and the empty language. A backslash makes the next character a
+// literal symbol, [abc], [a-z] and [^0-9] match one symbol of a class and .
+// any symbol. Any other character is a symbol. Without an alphabet to range
+// over, classes stand for the characters they list, and wildcards and
+// negated classes are rejected; see ParseRegexOver to give one.
+func ParseRegex(regex string) (*Regex, error) {
+	return ParseRegexOver(regex, nil)
+}
 
-	// Parse and convert regex to NFA using Thompson's construction
+// ParseRegexOver parses a regular expression like ParseRegex, expanding
+// character classes and wildcards into unions of the symbols of alphabet
+// they match, in alphabet order.
+func ParseRegexOver(regex string, alphabet []string) (*Regex, error) {
 	parser := &RegexParser{
-		input:        regex,
-		runes:        []rune(regex),
-		pos:          0,
-		stateCounter: 0,
+		input:    regex,
+		runes:    []rune(regex),
+		alphabet: alphabet,
 	}
-	fragment, err := parser.parseExpression()
+	node, err := parser.parseExpression()
 	if err != nil {
 		return nil, err
 	}
-	
+
 	if parser.pos < len(parser.runes) {
 		return nil, fmt.Errorf("unexpected character at position %d", parser.pos)
 	}
+	parser.expandClasses()
+	return node, nil
+}
 
-	return fragment.toFA(), nil
+// parseConversion parses the regex of a conversion like ParseRegexOver, except
+// that an empty regex stands for the empty language rather than ε.
+func parseConversion(regex string, alphabet []string) (*Regex, error) {
+	if regex == "" {
+		return &Regex{Op: RegexEmpty}, nil
+	}
+	return ParseRegexOver(regex, alphabet)
 }
 
-// Helper functions for regex operations
-func unionRegex(r1, r2 string) string {
-	if r1 == "∅" {
+// Helper functions for regex operations, dropping the ∅ and ε operands
+// that state elimination produces
+func unionRegex(r1, r2 *Regex) *Regex {
+	if r1.Op == RegexEmpty {
 		return r2
 	}
-	if r2 == "∅" {
+	if r2.Op == RegexEmpty {
 		return r1
 	}
-	if r1 == r2 {
+	if r1.key() == r2.key() {
 		return r1
 	}
-	return fmt.Sprintf("(%s∪%s)", r1, r2)
+	return &Regex{Op: RegexUnion, Left: r1, Right: r2}
 }
 
-func concatenateRegex(r1, r2 string) string {
-	if r1 == "∅" || r2 == "∅" {
-		return "∅"
+func concatenateRegex(r1, r2 *Regex) *Regex {
+	if r1.Op == RegexEmpty || r2.Op == RegexEmpty {
+		return &Regex{Op: RegexEmpty}
 	}
-	if r1 == "ε" {
+	if r1.Op == RegexEpsilon {
 		return r2
 	}
-	if r2 == "ε" {
+	if r2.Op == RegexEpsilon {
 		return r1
 	}
-
-	// Add parentheses if needed
-	needParens1 := strings.Contains(r1, "∪")
-	needParens2 := strings.Contains(r2, "∪")
-
-	result := ""
-	if needParens1 {
-		result += "(" + r1 + ")"
-	} else {
-		result += r1
-	}
-
-	if needParens2 {
-		result += "(" + r2 + ")"
-	} else {
-		result += r2
-	}
-
-	return result
-}
-
-func kleeneStarRegex(r string) string {
-	if r == "∅" || r == "ε" {
-		return "ε"
-	}
-
-	// Check if parentheses are needed
-	if len(r) == 1 || (strings.HasPrefix(r, "(") && strings.HasSuffix(r, ")")) {
-		return r + "*"
-	}
-	return "(" + r + ")*"
+	return &Regex{Op: RegexConcat, Left: r1, Right: r2}
 }
 
-func getStateIndexInList(states []string, state string) int {
-	for i, s := range states {
-		if s == state {
-			return i
-		}
-	}
-	return -1
-}
-
-
-func createEmptyNFA() *FA {
-	return &FA{
-		Alphabet:    []string{},
-		States:      []string{"q0"},
-		Initial:     "q0",
-		Acceptance:  []string{}, // No accepting states
-		Transitions: [][]any{{"@v"}},
-	}
-}
-
-func createEpsilonNFA() *FA {
-	return &FA{
-		Alphabet:    []string{},
-		States:      []string{"q0"},
-		Initial:     "q0",
-		Acceptance:  []string{"q0"}, // Initial state is accepting for epsilon
-		Transitions: [][]any{{"@v"}},
+func kleeneStarRegex(r *Regex) *Regex {
+	if r.Op == RegexEmpty || r.Op == RegexEpsilon {
+		return &Regex{Op: RegexEpsilon}
 	}
+	return &Regex{Op: RegexStar, Left: r}
 }
 
 func createCharacterNFA(char string) *FA {
@@ -246,35 +264,26 @@ type NFAFragment struct {
 	alphabet    map[string]bool
 }
 
-// Convert NFAFragment to FA
-func (f *NFAFragment) toFA() *FA {
+// Convert NFAFragment to FA over the given symbols
+func (f *NFAFragment) toFA(symbols []string) *FA {
 	// Handle empty language case
 	if f.end == "" {
+		row := make([]any, len(symbols))
+		for j := range row {
+			row[j] = "@v"
+		}
 		return &FA{
-			Alphabet:    []string{},
+			Alphabet:    append([]string{}, symbols...),
 			States:      []string{f.start},
 			Initial:     f.start,
 			Acceptance:  []string{}, // No accepting states for empty language
-			Transitions: [][]any{{"@v"}},
+			Transitions: [][]any{row},
 		}
 	}
 
-	// Build alphabet slice (regular symbols first, then epsilon)
-	alphabetSlice := make([]string, 0, len(f.alphabet))
-	for symbol := range f.alphabet {
-		if symbol != "@e" {
-			alphabetSlice = append(alphabetSlice, symbol)
-		}
-	}
-	// Add epsilon if it exists in transitions
-	hasEpsilon := false
-	for symbol := range f.alphabet {
-		if symbol == "@e" {
-			hasEpsilon = true
-			break
-		}
-	}
-	if hasEpsilon {
+	// Build alphabet slice (regular symbols first, then epsilon if it exists in transitions)
+	alphabetSlice := append([]string{}, symbols...)
+	if f.alphabet["@e"] {
 		alphabetSlice = append(alphabetSlice, "@e")
 	}
 
@@ -329,8 +338,23 @@ type RegexParser struct {
 	runes        []rune
 	pos          int
 	stateCounter int
+	alphabet     []string        // explicit alphabet for classes, if any
+	literals     []string        // without alphabet, symbols written in the expression, in order
+	seen         map[string]bool // the symbols in literals
+	classes      []regexClass    // classes to expand once parsing is done
 }
 
+// regexClass is a character class or wildcard awaiting expansion into node
+type regexClass struct {
+	node    *Regex
+	ranges  [][2]rune // inclusive bounds of the characters listed
+	negated bool
+}
+
+// maxClassWidth bounds the characters a class may list without an explicit
+// alphabet, since each of them becomes a symbol
+const maxClassWidth = 256
+
 // Generate unique state names
 func (p *RegexParser) newState() string {
 	state := fmt.Sprintf("q%d", p.stateCounter)
@@ -357,50 +381,49 @@ func (p *RegexParser) advance() rune {
 }
 
 // Parse expression (handles union with lowest precedence)
-func (p *RegexParser) parseExpression() (*NFAFragment, error) {
+func (p *RegexParser) parseExpression() (*Regex, error) {
 	left, err := p.parseSequence()
 	if err != nil {
 		return nil, err
 	}
 
-	for p.peek() == '∪' {
-		p.advance() // consume '∪'
+	for p.peek() == '∪' || p.peek() == '|' {
+		p.advance() // consume '∪' or '|'
 		right, err := p.parseSequence()
 		if err != nil {
 			return nil, err
 		}
-		left = p.union(left, right)
+		left = &Regex{Op: RegexUnion, Left: left, Right: right}
 	}
 
 	return left, nil
 }
 
 // Parse sequence (handles concatenation)
-func (p *RegexParser) parseSequence() (*NFAFragment, error) {
-	fragments := []*NFAFragment{}
+func (p *RegexParser) parseSequence() (*Regex, error) {
+	var result *Regex
 
-	for p.pos < len(p.runes) && p.peek() != ')' && p.peek() != '∪' {
-		fragment, err := p.parseFactor()
+	for p.pos < len(p.runes) && p.peek() != ')' && p.peek() != '∪' && p.peek() != '|' {
+		factor, err := p.parseFactor()
 		if err != nil {
 			return nil, err
 		}
-		fragments = append(fragments, fragment)
-	}
-
-	if len(fragments) == 0 {
-		return p.epsilon(), nil
+		if result == nil {
+			result = factor
+		} else {
+			result = &Regex{Op: RegexConcat, Left: result, Right: factor}
+		}
 	}
 
-	result := fragments[0]
-	for i := 1; i < len(fragments); i++ {
-		result = p.concatenate(result, fragments[i])
+	if result == nil {
+		return &Regex{Op: RegexEpsilon}, nil
 	}
 
 	return result, nil
 }
 
 // Parse factor (handles Kleene star and plus)
-func (p *RegexParser) parseFactor() (*NFAFragment, error) {
+func (p *RegexParser) parseFactor() (*Regex, error) {
 	base, err := p.parseAtom()
 	if err != nil {
 		return nil, err
@@ -409,9 +432,9 @@ func (p *RegexParser) parseFactor() (*NFAFragment, error) {
 	for p.peek() == '*' || p.peek() == '∗' || p.peek() == '+' {
 		op := p.advance()
 		if op == '*' || op == '∗' {
-			base = p.kleeneStar(base)
+			base = &Regex{Op: RegexStar, Left: base}
 		} else if op == '+' {
-			base = p.kleenePlus(base)
+			base = &Regex{Op: RegexPlus, Left: base}
 		}
 	}
 
@@ -419,7 +442,7 @@ func (p *RegexParser) parseFactor() (*NFAFragment, error) {
 }
 
 // Parse atom (basic elements)
-func (p *RegexParser) parseAtom() (*NFAFragment, error) {
+func (p *RegexParser) parseAtom() (*Regex, error) {
 	if p.pos >= len(p.runes) {
 		return nil, fmt.Errorf("unexpected end of input")
 	}
@@ -441,17 +464,191 @@ func (p *RegexParser) parseAtom() (*NFAFragment, error) {
 
 	if ch == 'ε' {
 		p.pos++
-		return p.epsilon(), nil
+		return &Regex{Op: RegexEpsilon}, nil
 	}
 
 	if ch == '∅' {
 		p.pos++
-		return p.empty(), nil
+		return &Regex{Op: RegexEmpty}, nil
+	}
+
+	if ch == '\\' {
+		p.pos++
+		if p.pos >= len(p.runes) {
+			return nil, fmt.Errorf("dangling escape at position %d", p.pos-1)
+		}
+		escaped := p.runes[p.pos]
+		p.pos++
+		if escaped == 'e' {
+			return &Regex{Op: RegexEpsilon}, nil
+		}
+		return p.symbol(string(escaped)), nil
+	}
+
+	if ch == '.' {
+		p.pos++
+		return p.class(nil, true, p.pos-1)
+	}
+
+	if ch == '[' {
+		return p.parseClass()
 	}
 
 	// Regular character
 	p.pos++
-	return p.character(string(ch)), nil
+	return p.symbol(string(ch)), nil
+}
+
+// Parse character class: [abc], [a-z0-9] or [^...], with \ escaping any
+// character inside
+func (p *RegexParser) parseClass() (*Regex, error) {
+	start := p.pos
+	p.pos++ // consume '['
+	negated := false
+	if p.peek() == '^' {
+		negated = true
+		p.pos++
+	}
+
+	var ranges [][2]rune
+	for {
+		if p.pos >= len(p.runes) {
+			return nil, fmt.Errorf("unterminated character class at position %d", start)
+		}
+		if p.runes[p.pos] == ']' {
+			p.pos++
+			break
+		}
+		low, err := p.classRune()
+		if err != nil {
+			return nil, err
+		}
+		high := low
+		if p.peek() == '-' && p.pos+1 < len(p.runes) && p.runes[p.pos+1] != ']' {
+			p.pos++ // consume '-'
+			if high, err = p.classRune(); err != nil {
+				return nil, err
+			}
+			if high < low {
+				return nil, fmt.Errorf("invalid range %c-%c in character class at position %d", low, high, start)
+			}
+		}
+		ranges = append(ranges, [2]rune{low, high})
+	}
+	return p.class(ranges, negated, start)
+}
+
+// Read one possibly escaped character of a class
+func (p *RegexParser) classRune() (rune, error) {
+	ch := p.advance()
+	if ch != '\\' {
+		return ch, nil
+	}
+	if p.pos >= len(p.runes) {
+		return 0, fmt.Errorf("dangling escape at position %d", p.pos-1)
+	}
+	return p.advance(), nil
+}
+
+// Create a symbol node, remembering the symbol for class expansion when no
+// alphabet was given
+func (p *RegexParser) symbol(symbol string) *Regex {
+	if len(p.alphabet) == 0 && !p.seen[symbol] {
+		if p.seen == nil {
+			p.seen = make(map[string]bool)
+		}
+		p.seen[symbol] = true
+		p.literals = append(p.literals, symbol)
+	}
+	return &Regex{Op: RegexSymbol, Symbol: symbol}
+}
+
+// Create a placeholder for a class, expanded once the whole alphabet is known.
+// A wildcard is a negated empty class. Without an alphabet, the characters of
+// a class become symbols, and wildcards and negated classes have nothing to
+// range over
+func (p *RegexParser) class(ranges [][2]rune, negated bool, start int) (*Regex, error) {
+	node := &Regex{Op: RegexEmpty}
+	if len(p.alphabet) == 0 {
+		if negated {
+			return nil, fmt.Errorf("wildcard or negated character class at position %d needs an explicit alphabet", start)
+		}
+		width := 0
+		for _, bounds := range ranges {
+			width += int(bounds[1]-bounds[0]) + 1
+			if width > maxClassWidth {
+				return nil, fmt.Errorf("character class at position %d lists more than %d characters without an explicit alphabet", start, maxClassWidth)
+			}
+		}
+		for _, bounds := range ranges {
+			for r := bounds[0]; r <= bounds[1]; r++ {
+				p.symbol(string(r))
+			}
+		}
+	}
+	p.classes = append(p.classes, regexClass{node: node, ranges: ranges, negated: negated})
+	return node, nil
+}
+
+// Check whether a symbol is one of the characters listed in a class
+func (c regexClass) lists(symbol string) bool {
+	r, size := utf8.DecodeRuneInString(symbol)
+	if size == 0 || size != len(symbol) {
+		return false
+	}
+	for _, bounds := range c.ranges {
+		if bounds[0] <= r && r <= bounds[1] {
+			return true
+		}
+	}
+	return false
+}
+
+// Expand every class into the union of the alphabet symbols it matches, ∅ if none
+func (p *RegexParser) expandClasses() {
+	alphabet := p.alphabet
+	if len(alphabet) == 0 {
+		alphabet = p.literals
+	}
+	for _, class := range p.classes {
+		var expansion *Regex
+		for _, member := range alphabet {
+			if class.lists(member) == class.negated {
+				continue
+			}
+			symbol := &Regex{Op: RegexSymbol, Symbol: member}
+			if expansion == nil {
+				expansion = symbol
+			} else {
+				expansion = &Regex{Op: RegexUnion, Left: expansion, Right: symbol}
+			}
+		}
+		if expansion != nil {
+			*class.node = *expansion
+		}
+	}
+}
+
+// Thompson's construction, building fragments bottom-up
+func (p *RegexParser) thompson(node *Regex) *NFAFragment {
+	switch node.Op {
+	case RegexEmpty:
+		return p.empty()
+	case RegexEpsilon:
+		return p.epsilon()
+	case RegexSymbol:
+		return p.character(node.Symbol)
+	case RegexUnion:
+		left := p.thompson(node.Left)
+		return p.union(left, p.thompson(node.Right))
+	case RegexConcat:
+		left := p.thompson(node.Left)
+		return p.concatenate(left, p.thompson(node.Right))
+	case RegexStar:
+		return p.kleeneStar(p.thompson(node.Left))
+	default:
+		return p.kleenePlus(p.thompson(node.Left))
+	}
 }
 
 // Thompson construction primitives
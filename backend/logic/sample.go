@@ -0,0 +1,102 @@
+package logic
+
+import (
+	"math/big"
+	"math/rand"
+	"strings"
+)
+
+// Sampler draws words of a given length uniformly at random from the language
+// of an FA, or from its complement over the FA's alphabet. Draws are
+// reproducible for a given seed.
+type Sampler struct {
+	dfa        *automaton
+	complement *automaton
+	rng        *rand.Rand
+}
+
+// NewSampler prepares a sampler for fa seeded with seed.
+func NewSampler(fa *FA, seed int64) (*Sampler, error) {
+	a, err := compile(fa)
+	if err != nil {
+		return nil, err
+	}
+	dfa, _ := determinize(a)
+
+	// Subset construction yields a complete DFA, so flipping acceptance
+	// complements the language
+	complement := dfa.clone()
+	for q := range complement.accept {
+		complement.accept[q] = !complement.accept[q]
+	}
+
+	return &Sampler{
+		dfa:        dfa,
+		complement: complement,
+		rng:        rand.New(rand.NewSource(seed)),
+	}, nil
+}
+
+// Accepted draws n accepted words of the given length, independently and
+// uniformly among all of them. It returns no words when there are none.
+func (s *Sampler) Accepted(length, n int) []string {
+	return s.draw(s.dfa, length, n)
+}
+
+// Rejected draws n words of the given length over the FA's alphabet that the
+// FA rejects, independently and uniformly among all of them. It returns no
+// words when there are none.
+func (s *Sampler) Rejected(length, n int) []string {
+	return s.draw(s.complement, length, n)
+}
+
+// draw samples words from a complete DFA. ways[k][q] counts the accepted words
+// of length k starting at q; each symbol is then picked with probability
+// proportional to the number of completions it leaves.
+func (s *Sampler) draw(dfa *automaton, length, n int) []string {
+	ways := make([][]*big.Int, length+1)
+	ways[0] = make([]*big.Int, len(dfa.states))
+	for q := range dfa.states {
+		ways[0][q] = new(big.Int)
+		if dfa.accept[q] {
+			ways[0][q].SetInt64(1)
+		}
+	}
+	for k := 1; k <= length; k++ {
+		ways[k] = make([]*big.Int, len(dfa.states))
+		for q := range dfa.states {
+			ways[k][q] = new(big.Int)
+			for symbol := range dfa.alphabet {
+				if p := dfa.next(q, symbol); p >= 0 {
+					ways[k][q].Add(ways[k][q], ways[k-1][p])
+				}
+			}
+		}
+	}
+
+	words := []string{}
+	if ways[length][dfa.initial].Sign() == 0 {
+		return words
+	}
+	for range n {
+		var b strings.Builder
+		q := dfa.initial
+		for k := length; k > 0; k-- {
+			pick := new(big.Int).Rand(s.rng, ways[k][q])
+			for symbol, name := range dfa.alphabet {
+				p := dfa.next(q, symbol)
+				if p < 0 {
+					continue
+				}
+				if pick.Cmp(ways[k-1][p]) < 0 {
+					b.WriteString(name)
+					q = p
+					break
+				}
+				pick.Sub(pick, ways[k-1][p])
+			}
+		}
+		words = append(words, b.String())
+	}
+	return words
+}
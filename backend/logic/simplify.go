@@ -0,0 +1,282 @@
+package logic
+
+import "sort"
+
+// Simplify returns an equivalent expression rewritten until no rule applies:
+//
+//	∅ ∪ r = r      ∅r = r∅ = ∅      ∅* = ε* = ε      εr = rε = r
+//	r ∪ r = r      (r*)* = (r+)* = (r*)+ = r*      (r+)+ = r+
+//	rr* = r*r = r+      r*r* = r*      ε ∪ r+ = r*      r+ = r* if ε ∈ r
+//	ε ∪ r = r if ε ∈ r      r ∪ r* = r+ ∪ r* = r*      (ε ∪ r)* = r*
+//	rs ∪ rt = r(s ∪ t)      sr ∪ tr = (s ∪ t)r
+//
+// Unions and concatenations are flattened, and the alternatives of a union
+// are sorted, so expressions equal up to associativity and commutativity of
+// union come out the same. The receiver is left untouched.
+func (r *Regex) Simplify() *Regex {
+	for {
+		simplified := r.simplifyOnce()
+		if simplified.key() == r.key() {
+			return simplified
+		}
+		r = simplified
+	}
+}
+
+// simplifyOnce simplifies the children of the expression, then applies the
+// rules at its root.
+func (r *Regex) simplifyOnce() *Regex {
+	switch r.Op {
+	case RegexUnion:
+		var alternatives []*Regex
+		for _, alternative := range r.alternatives() {
+			alternatives = append(alternatives, alternative.simplifyOnce())
+		}
+		return simplifiedUnion(alternatives)
+	case RegexConcat:
+		var factors []*Regex
+		for _, factor := range r.factors() {
+			factors = append(factors, factor.simplifyOnce())
+		}
+		return simplifiedConcat(factors)
+	case RegexStar:
+		return simplifiedStar(r.Left.simplifyOnce())
+	case RegexPlus:
+		return simplifiedPlus(r.Left.simplifyOnce())
+	default:
+		return r
+	}
+}
+
+// alternatives returns the operands of a chain of unions.
+func (r *Regex) alternatives() []*Regex {
+	if r.Op != RegexUnion {
+		return []*Regex{r}
+	}
+	return append(r.Left.alternatives(), r.Right.alternatives()...)
+}
+
+// factors returns the operands of a chain of concatenations.
+func (r *Regex) factors() []*Regex {
+	if r.Op != RegexConcat {
+		return []*Regex{r}
+	}
+	return append(r.Left.factors(), r.Right.factors()...)
+}
+
+func simplifiedUnion(alternatives []*Regex) *Regex {
+	// Flatten, dropping ∅ and duplicates, in key order
+	unique := make(map[string]*Regex)
+	for _, alternative := range alternatives {
+		for _, a := range alternative.alternatives() {
+			if a.Op != RegexEmpty {
+				unique[a.key()] = a
+			}
+		}
+	}
+	keys := make([]string, 0, len(unique))
+	for key := range unique {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	// ε ∪ r+ = r*
+	if _, ok := unique["ε"]; ok {
+		for _, key := range keys {
+			if a := unique[key]; a.Op == RegexPlus {
+				delete(unique, "ε")
+				delete(unique, key)
+				star := &Regex{Op: RegexStar, Left: a.Left}
+				unique[star.key()] = star
+				keys = append(keys, star.key())
+				break
+			}
+		}
+	}
+
+	// Drop alternatives contained in others: r and r+ in r*, r in r+ and ε
+	// in any nullable alternative
+	for _, key := range keys {
+		a, ok := unique[key]
+		if !ok {
+			continue
+		}
+		switch a.Op {
+		case RegexStar:
+			delete(unique, a.Left.key())
+			delete(unique, (&Regex{Op: RegexPlus, Left: a.Left}).key())
+		case RegexPlus:
+			delete(unique, a.Left.key())
+		}
+		if key != "ε" && a.nullable() {
+			delete(unique, "ε")
+		}
+	}
+
+	alternatives = nil
+	for _, key := range keys {
+		if a, ok := unique[key]; ok {
+			alternatives = append(alternatives, a)
+			delete(unique, key)
+		}
+	}
+	alternatives = factorAlternatives(alternatives, false)
+	alternatives = factorAlternatives(alternatives, true)
+
+	if len(alternatives) == 0 {
+		return &Regex{Op: RegexEmpty}
+	}
+	sort.Slice(alternatives, func(i, j int) bool {
+		return alternatives[i].key() < alternatives[j].key()
+	})
+	result := alternatives[len(alternatives)-1]
+	for i := len(alternatives) - 2; i >= 0; i-- {
+		result = &Regex{Op: RegexUnion, Left: alternatives[i], Right: result}
+	}
+	return result
+}
+
+// factorAlternatives merges the alternatives sharing their first factor, or
+// their last one if suffix is set, into that factor concatenated with the
+// union of what remains of them.
+func factorAlternatives(alternatives []*Regex, suffix bool) []*Regex {
+	end := func(factors []*Regex) *Regex {
+		if suffix {
+			return factors[len(factors)-1]
+		}
+		return factors[0]
+	}
+	rest := func(factors []*Regex) []*Regex {
+		if suffix {
+			return factors[:len(factors)-1]
+		}
+		return factors[1:]
+	}
+
+	// Group alternatives by shared factor, in order of first appearance
+	var order []string
+	groups := make(map[string][][]*Regex)
+	for _, a := range alternatives {
+		factors := a.factors()
+		key := end(factors).key()
+		if _, ok := groups[key]; !ok {
+			order = append(order, key)
+		}
+		groups[key] = append(groups[key], factors)
+	}
+
+	var result []*Regex
+	for _, key := range order {
+		group := groups[key]
+		if len(group) == 1 {
+			result = append(result, simplifiedConcat(group[0]))
+			continue
+		}
+		var remainders []*Regex
+		for _, factors := range group {
+			remainders = append(remainders, simplifiedConcat(rest(factors)))
+		}
+		shared := end(group[0])
+		remainder := simplifiedUnion(remainders)
+		if suffix {
+			result = append(result, simplifiedConcat([]*Regex{remainder, shared}))
+		} else {
+			result = append(result, simplifiedConcat([]*Regex{shared, remainder}))
+		}
+	}
+	return result
+}
+
+func simplifiedConcat(factors []*Regex) *Regex {
+	// Flatten, dropping ε; ∅ absorbs everything
+	var flat []*Regex
+	for _, factor := range factors {
+		for _, f := range factor.factors() {
+			switch f.Op {
+			case RegexEmpty:
+				return &Regex{Op: RegexEmpty}
+			case RegexEpsilon:
+				continue
+			}
+			flat = append(flat, f)
+		}
+	}
+
+	// rr* = r*r = r+ and r*r* = r*, where r may span several factors
+	var merged []*Regex
+	for _, f := range flat {
+		n := len(merged)
+		if f.Op == RegexStar {
+			if n > 0 && merged[n-1].key() == f.key() {
+				continue
+			}
+			operand := f.Left.factors()
+			if k := len(operand); k <= n && sameFactors(merged[n-k:], operand) {
+				merged = append(merged[:n-k], simplifiedPlus(f.Left))
+				continue
+			}
+		}
+		if n > 0 && merged[n-1].Op == RegexStar && merged[n-1].Left.key() == f.key() {
+			merged[n-1] = simplifiedPlus(f)
+			continue
+		}
+		merged = append(merged, f)
+	}
+
+	if len(merged) == 0 {
+		return &Regex{Op: RegexEpsilon}
+	}
+	result := merged[len(merged)-1]
+	for i := len(merged) - 2; i >= 0; i-- {
+		result = &Regex{Op: RegexConcat, Left: merged[i], Right: result}
+	}
+	return result
+}
+
+// sameFactors reports whether two sequences of factors are equal.
+func sameFactors(a, b []*Regex) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].key() != b[i].key() {
+			return false
+		}
+	}
+	return true
+}
+
+func simplifiedStar(operand *Regex) *Regex {
+	switch operand.Op {
+	case RegexEmpty, RegexEpsilon:
+		return &Regex{Op: RegexEpsilon}
+	case RegexStar:
+		return operand
+	case RegexPlus:
+		return simplifiedStar(operand.Left)
+	case RegexUnion:
+		// (ε ∪ r)* = r*
+		var alternatives []*Regex
+		for _, a := range operand.alternatives() {
+			if a.Op != RegexEpsilon {
+				alternatives = append(alternatives, a)
+			}
+		}
+		operand = simplifiedUnion(alternatives)
+		if operand.Op != RegexUnion {
+			return simplifiedStar(operand)
+		}
+	}
+	return &Regex{Op: RegexStar, Left: operand}
+}
+
+func simplifiedPlus(operand *Regex) *Regex {
+	switch operand.Op {
+	case RegexEmpty, RegexEpsilon, RegexStar, RegexPlus:
+		return operand
+	}
+	if operand.nullable() {
+		return simplifiedStar(operand)
+	}
+	return &Regex{Op: RegexPlus, Left: operand}
+}
@@ -0,0 +1,154 @@
+package logic
+
+import (
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// RegexOp is the operator at the root of a Regex.
+type RegexOp int
+
+const (
+	RegexEmpty   RegexOp = iota // ∅
+	RegexEpsilon                // ε
+	RegexSymbol                 // a single alphabet symbol
+	RegexUnion                  // left ∪ right
+	RegexConcat                 // left right
+	RegexStar                   // left*
+	RegexPlus                   // left+
+)
+
+// Regex is the syntax tree of a regular expression, shared by the parser,
+// the printer and every conversion to and from FAs. Symbol is only set on
+// RegexSymbol nodes, and unary operators keep their operand in Left.
+type Regex struct {
+	Op          RegexOp
+	Symbol      string
+	Left, Right *Regex
+}
+
+// nullable reports whether the expression matches the empty string.
+func (n *Regex) nullable() bool {
+	switch n.Op {
+	case RegexEpsilon, RegexStar:
+		return true
+	case RegexUnion:
+		return n.Left.nullable() || n.Right.nullable()
+	case RegexConcat:
+		return n.Left.nullable() && n.Right.nullable()
+	case RegexPlus:
+		return n.Left.nullable()
+	default:
+		return false
+	}
+}
+
+// alphabet returns the symbols of the expression in order of first appearance.
+func (n *Regex) alphabet() []string {
+	var symbols []string
+	seen := make(map[string]bool)
+	var walk func(*Regex)
+	walk = func(n *Regex) {
+		if n == nil {
+			return
+		}
+		if n.Op == RegexSymbol && !seen[n.Symbol] {
+			seen[n.Symbol] = true
+			symbols = append(symbols, n.Symbol)
+		}
+		walk(n.Left)
+		walk(n.Right)
+	}
+	walk(n)
+	return symbols
+}
+
+// alphabetOver returns alphabet followed by the symbols of the expression it
+// lacks, in order of first appearance.
+func (n *Regex) alphabetOver(alphabet []string) []string {
+	symbols := append([]string{}, alphabet...)
+	for _, symbol := range n.alphabet() {
+		if !Contains(symbols, symbol) {
+			symbols = append(symbols, symbol)
+		}
+	}
+	return symbols
+}
+
+// key returns a fully parenthesized rendering of the expression with quoted
+// symbols, so that structurally equal expressions, and only those, get equal
+// keys even when symbols span several characters or contain operators.
+func (n *Regex) key() string {
+	switch n.Op {
+	case RegexEmpty:
+		return "∅"
+	case RegexEpsilon:
+		return "ε"
+	case RegexSymbol:
+		return strconv.Quote(n.Symbol)
+	case RegexUnion:
+		return "(" + n.Left.key() + "∪" + n.Right.key() + ")"
+	case RegexConcat:
+		return "(" + n.Left.key() + n.Right.key() + ")"
+	case RegexStar:
+		return "(" + n.Left.key() + ")*"
+	default:
+		return "(" + n.Left.key() + ")+"
+	}
+}
+
+// String renders the expression with as few parentheses as the precedence of
+// its operators allows: star and plus bind tighter than concatenation, which
+// binds tighter than union.
+func (n *Regex) String() string {
+	switch n.Op {
+	case RegexEmpty:
+		return "∅"
+	case RegexEpsilon:
+		return "ε"
+	case RegexSymbol:
+		return escapeSymbol(n.Symbol)
+	case RegexUnion:
+		return n.Left.String() + "∪" + n.Right.String()
+	case RegexConcat:
+		return n.Left.operand(RegexConcat) + n.Right.operand(RegexConcat)
+	case RegexStar:
+		return n.Left.operand(RegexStar) + "*"
+	default:
+		return n.Left.operand(RegexStar) + "+"
+	}
+}
+
+// escapeSymbol renders a symbol that the parser would read as an operator
+// with a backslash, so that printed expressions parse back to themselves.
+func escapeSymbol(symbol string) string {
+	if utf8.RuneCountInString(symbol) == 1 && strings.ContainsAny(symbol, `()∪|*∗+ε∅.[\`) {
+		return `\` + symbol
+	}
+	return symbol
+}
+
+// operand renders the expression as an operand of parent, parenthesized if it
+// binds more loosely.
+func (n *Regex) operand(parent RegexOp) string {
+	if n.precedence() < precedenceOf(parent) {
+		return "(" + n.String() + ")"
+	}
+	return n.String()
+}
+
+func (n *Regex) precedence() int { return precedenceOf(n.Op) }
+
+func precedenceOf(op RegexOp) int {
+	switch op {
+	case RegexUnion:
+		return 0
+	case RegexConcat:
+		return 1
+	case RegexStar, RegexPlus:
+		return 2
+	default:
+		return 3
+	}
+}
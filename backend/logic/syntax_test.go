@@ -0,0 +1,354 @@
+package logic
+
+import (
+	"math/rand"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// regexSymbols are the symbols of random expressions, most of which the
+// printer has to escape.
+var regexSymbols = []string{"a", "b", "*", "(", "|", ".", `\`}
+
+// randomRegex returns an expression of the given depth at most over
+// regexSymbols.
+func randomRegex(r *rand.Rand, depth int) *Regex {
+	if depth == 0 || r.Intn(4) == 0 {
+		switch r.Intn(10) {
+		case 0:
+			return &Regex{Op: RegexEmpty}
+		case 1:
+			return &Regex{Op: RegexEpsilon}
+		default:
+			return &Regex{Op: RegexSymbol, Symbol: regexSymbols[r.Intn(len(regexSymbols))]}
+		}
+	}
+	switch op := RegexUnion + RegexOp(r.Intn(4)); op {
+	case RegexUnion, RegexConcat:
+		return &Regex{Op: op, Left: randomRegex(r, depth-1), Right: randomRegex(r, depth-1)}
+	default:
+		return &Regex{Op: op, Left: randomRegex(r, depth-1)}
+	}
+}
+
+// sameRegexLanguage checks that got and want match the same words of length
+// at most 3 over regexSymbols, through the NFAs of their Thompson construction.
+func sameRegexLanguage(t *testing.T, got, want *Regex) {
+	t.Helper()
+	gotNFA, err := got.ToNFA(Thompson)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantNFA, err := want.ToNFA(Thompson)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, word := range words(regexSymbols, 3) {
+		if simulate(gotNFA, word) != simulate(wantNFA, word) {
+			t.Fatalf("%s and %s differ on %q", got, want, strings.Join(word, ""))
+		}
+	}
+}
+
+func TestParsePrintParse(t *testing.T) {
+	tests := []struct {
+		regex   string
+		printed string
+	}{
+		{"(a∪b)*abb", "(a∪b)*abb"},
+		{"a|b", "a∪b"},
+		{"a∗b+", "a*b+"},
+		{"(ab)*∪ε", "(ab)*∪ε"},
+		{`\e∪∅`, "ε∪∅"},
+		{`a\*b`, `a\*b`},
+		{`\(a\)`, `\(a\)`},
+		{`a\|b`, `a\|b`},
+		{`\.\+`, `\.\+`},
+		{`\\*`, `\\*`},
+		{`\∪\ε\∅`, `\∪\ε\∅`},
+		{"[a-c]", "a∪b∪c"},
+	}
+	for _, test := range tests {
+		node, err := ParseRegex(test.regex)
+		if err != nil {
+			t.Fatalf("%s: %v", test.regex, err)
+		}
+		if printed := node.String(); printed != test.printed {
+			t.Errorf("%s: printed as %s, want %s", test.regex, printed, test.printed)
+		}
+		again, err := ParseRegex(node.String())
+		if err != nil {
+			t.Fatalf("%s: reparsing %s: %v", test.regex, node.String(), err)
+		}
+		if again.key() != node.key() {
+			t.Errorf("%s: reparsed as %s, want %s", test.regex, again.key(), node.key())
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	// Without an alphabet, wildcards and negated or very wide classes have
+	// nothing to expand into
+	for _, regex := range []string{"(a", "a)", `a\`, "[ab", "[b-a]", "a∪)", ".", "a[^b]", "[\u0100-\uffff]"} {
+		if node, err := ParseRegex(regex); err == nil {
+			t.Errorf("%s: parsed as %s", regex, node)
+		}
+	}
+}
+
+func TestPrintRoundTrip(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 500; i++ {
+		node := randomRegex(r, 4)
+		printed := node.String()
+		parsed, err := ParseRegex(printed)
+		if err != nil {
+			t.Fatalf("%s: %v", printed, err)
+		}
+		// Union and concatenation are associative, so the printer drops the
+		// parentheses the tree may have had and only the rendering is stable
+		if again := parsed.String(); again != printed {
+			t.Fatalf("%s: reparsed as %s", printed, again)
+		}
+		sameRegexLanguage(t, parsed, node)
+	}
+}
+
+func TestSimplify(t *testing.T) {
+	r := rand.New(rand.NewSource(2))
+	for i := 0; i < 500; i++ {
+		node := randomRegex(r, 4)
+		simplified := node.Simplify()
+		if simplified.Size() > node.Size() {
+			t.Errorf("%s grew into %s", node, simplified)
+		}
+		if again := simplified.Simplify(); again.key() != simplified.key() {
+			t.Errorf("%s: simplified to %s, then to %s", node, simplified, again)
+		}
+		sameRegexLanguage(t, simplified, node)
+	}
+}
+
+func TestParseClasses(t *testing.T) {
+	tests := []struct {
+		regex    string
+		alphabet []string
+		printed  string
+	}{
+		{"[ba]*c", nil, "(b∪a)*c"},
+		{"[a-c]", []string{"a", "b", "c", "d"}, "a∪b∪c"},
+		{"[a-bd]x", []string{"a", "b", "c", "d", "x"}, "(a∪b∪d)x"},
+		{".", []string{"a", "b", "c"}, "a∪b∪c"},
+		{"[^b]", []string{"a", "b", "c"}, "a∪c"},
+		{"[^a-c]*", []string{"a", "b", "c", "d"}, "d*"},
+		{"[^abc]", []string{"a", "b", "c"}, "∅"},
+		{"[x-z]", []string{"a", "b"}, "∅"},
+		{"[a]", []string{"aa", "a"}, "a"},
+	}
+	for _, test := range tests {
+		node, err := ParseRegexOver(test.regex, test.alphabet)
+		if err != nil {
+			t.Fatalf("%s: %v", test.regex, err)
+		}
+		if printed := node.String(); printed != test.printed {
+			t.Errorf("%s over %v: printed as %s, want %s", test.regex, test.alphabet, printed, test.printed)
+		}
+	}
+}
+
+// regexAlgorithms are the constructions of NFAs from expressions.
+var regexAlgorithms = []RegexAlgorithm{Thompson, Glushkov, Antimirov}
+
+func TestConstructions(t *testing.T) {
+	r := rand.New(rand.NewSource(3))
+	for i := 0; i < 300; i++ {
+		node := randomRegex(r, 4)
+		nfas := make(map[RegexAlgorithm]*FA)
+		for _, algorithm := range regexAlgorithms {
+			nfa, err := node.ToNFA(algorithm)
+			if err != nil {
+				t.Fatalf("%s: %s: %v", node, algorithm, err)
+			}
+			nfas[algorithm] = nfa
+		}
+
+		glushkov := nfas[Glushkov]
+		if len(glushkov.States) != node.Size()+1 {
+			t.Errorf("%s: %d Glushkov states for %d positions", node, len(glushkov.States), node.Size())
+		}
+		if slices.Contains(glushkov.Alphabet, epsilonSymbol) {
+			t.Errorf("%s: Glushkov automaton has epsilon transitions", node)
+		}
+		if antimirov := nfas[Antimirov]; len(antimirov.States) > len(glushkov.States) {
+			t.Errorf("%s: %d Antimirov states, more than %d Glushkov states", node, len(antimirov.States), len(glushkov.States))
+		}
+
+		for _, word := range words(regexSymbols, 3) {
+			want := simulate(nfas[Thompson], word)
+			for _, algorithm := range regexAlgorithms[1:] {
+				if simulate(nfas[algorithm], word) != want {
+					t.Fatalf("%s: %s differs from Thompson on %q", node, algorithm, strings.Join(word, ""))
+				}
+			}
+		}
+	}
+
+	if _, err := RegexToNFAWith("a", "bogus", nil); err == nil {
+		t.Error("built an NFA with an unknown construction")
+	}
+	if _, err := RegexToNFAWith("", "bogus", nil); err == nil {
+		t.Error("built an NFA for the empty regex with an unknown construction")
+	}
+}
+
+// Symbols of FA alphabets may span several characters, which must not make
+// them collide with the expressions they spell out
+func TestMultiCharacterSymbols(t *testing.T) {
+	symbol := func(s string) *Regex { return &Regex{Op: RegexSymbol, Symbol: s} }
+	concat := func(l, r *Regex) *Regex { return &Regex{Op: RegexConcat, Left: l, Right: r} }
+	union := func(l, r *Regex) *Regex { return &Regex{Op: RegexUnion, Left: l, Right: r} }
+	for _, pair := range [][2]*Regex{
+		{symbol("(ab)"), concat(symbol("a"), symbol("b"))},
+		{symbol("a∪b"), union(symbol("a"), symbol("b"))},
+		{concat(symbol("ab"), symbol("c")), concat(symbol("a"), symbol("bc"))},
+	} {
+		if pair[0].key() == pair[1].key() {
+			t.Errorf("%s and %s share key %s", pair[0], pair[1], pair[0].key())
+		}
+	}
+
+	// x(ab)(c) ∪ y(a)(bc): after x and y the constructions reach derivatives
+	// that spell alike but only match ab·c and a·bc respectively
+	alphabet := []string{"x", "y", "a", "b", "c", "ab", "bc"}
+	node := union(
+		concat(symbol("x"), concat(symbol("ab"), symbol("c"))),
+		concat(symbol("y"), concat(symbol("a"), symbol("bc"))))
+	want := func(word []string) bool {
+		return slices.Equal(word, []string{"x", "ab", "c"}) || slices.Equal(word, []string{"y", "a", "bc"})
+	}
+	nfa, err := node.ToNFAOver(Antimirov, alphabet)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, word := range words(alphabet, 3) {
+		if simulate(nfa, word) != want(word) {
+			t.Errorf("Antimirov automaton is wrong on %v", word)
+		}
+		derivative := node.normalize()
+		for _, symbol := range word {
+			derivative = derivative.derivative(symbol)
+		}
+		if derivative.nullable() != want(word) {
+			t.Errorf("derivative is wrong on %v", word)
+		}
+	}
+
+	// State elimination of p -ab-> f and p -a-> m -b-> f must keep both paths
+	fa := &FA{Alphabet: []string{"ab", "a", "b"}, States: []string{"p", "m", "f"}, Initial: "p",
+		Acceptance: []string{"f"}, Transitions: [][]any{
+			{"f", "m", noState},
+			{noState, noState, "f"},
+			{noState, noState, noState},
+		}}
+	regex, err := RegexFromFA(fa)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nfa, err = regex.ToNFAOver(Thompson, fa.Alphabet)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, word := range words(fa.Alphabet, 3) {
+		if simulate(nfa, word) != simulate(fa, word) {
+			t.Errorf("%s is wrong on %v", regex, word)
+		}
+	}
+}
+
+func TestConstructionsOverAlphabet(t *testing.T) {
+	alphabet := []string{"a", "b", "c"}
+	for _, algorithm := range regexAlgorithms {
+		for _, regex := range []string{"", "a*", "[^a]b"} {
+			nfa, err := RegexToNFAWith(regex, algorithm, alphabet)
+			if err != nil {
+				t.Fatalf("%s: %s: %v", regex, algorithm, err)
+			}
+			for _, symbol := range alphabet {
+				if !slices.Contains(nfa.Alphabet, symbol) {
+					t.Errorf("%s: %s automaton lacks symbol %s of %v", regex, algorithm, symbol, nfa.Alphabet)
+				}
+			}
+		}
+	}
+}
+
+// An empty regex is the empty language through every conversion, as it has
+// always been for RegexToNFA
+func TestEmptyRegex(t *testing.T) {
+	alphabet := []string{"a", "b"}
+	for _, algorithm := range regexAlgorithms {
+		nfa, err := RegexToNFAWith("", algorithm, alphabet)
+		if err != nil {
+			t.Fatalf("%s: %v", algorithm, err)
+		}
+		if simulate(nfa, nil) || simulate(nfa, []string{"a"}) {
+			t.Errorf("%s automaton of the empty regex is not ∅", algorithm)
+		}
+	}
+
+	dfa, _, err := RegexToDFA("", alphabet)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if simulate(dfa, nil) || simulate(dfa, []string{"a"}) {
+		t.Error("derivative automaton of the empty regex is not ∅")
+	}
+
+	steps, err := Derivatives("", "a", alphabet)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(steps) != 1 || steps[0].Derivative != "∅" || steps[0].Nullable {
+		t.Errorf("derivatives of the empty regex by a: %+v", steps)
+	}
+}
+
+func TestDerivatives(t *testing.T) {
+	r := rand.New(rand.NewSource(4))
+	for i := 0; i < 200; i++ {
+		node := randomRegex(r, 4)
+		regex := node.String()
+		nfa, err := node.ToNFA(Thompson)
+		if err != nil {
+			t.Fatal(err)
+		}
+		dfa, _, err := RegexToDFA(regex, nil)
+		if err != nil {
+			t.Fatalf("%s: %v", regex, err)
+		}
+		if !mustCompile(t, dfa).isDeterministic() {
+			t.Fatalf("%s: derivative automaton is nondeterministic", regex)
+		}
+
+		for _, word := range words(regexSymbols, 3) {
+			want := simulate(nfa, word)
+			if simulate(dfa, word) != want {
+				t.Fatalf("%s: DFA differs from Thompson on %q", regex, strings.Join(word, ""))
+			}
+			if len(word) == 0 {
+				continue
+			}
+			steps, err := Derivatives(regex, strings.Join(word, ""), nil)
+			if err != nil {
+				t.Fatalf("%s: %v", regex, err)
+			}
+			if len(steps) != len(word) {
+				t.Fatalf("%s: %d derivatives of %q", regex, len(steps), strings.Join(word, ""))
+			}
+			if last := steps[len(steps)-1]; last.Nullable != want {
+				t.Fatalf("%s: derivative %s by %q is nullable: %v", regex, last.Derivative, strings.Join(word, ""), last.Nullable)
+			}
+		}
+	}
+}
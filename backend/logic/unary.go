@@ -0,0 +1,150 @@
+package logic
+
+// Reverse returns an FA accepting the reversal of every string accepted by fa.
+// Transitions are flipped and the original initial state becomes the only
+// accepting one; a fresh initial state S with epsilon transitions to the
+// original accepting states is added unless there is exactly one of them.
+func Reverse(fa *FA) (*FA, error) {
+	a, err := compile(fa)
+	if err != nil {
+		return nil, err
+	}
+	return a.reverse().toFA(), nil
+}
+
+// reverse builds the reversed automaton, keeping state indices.
+func (a *automaton) reverse() *automaton {
+	result := newAutomaton(a.alphabet)
+	for _, name := range a.states {
+		result.addState(name, false)
+	}
+	result.accept[a.initial] = true
+
+	var accepting []int
+	for q := range a.states {
+		if a.accept[q] {
+			accepting = append(accepting, q)
+		}
+		for symbol, targets := range a.delta[q] {
+			for _, p := range targets {
+				result.addTransition(p, symbol, q)
+			}
+		}
+		for _, p := range a.eps[q] {
+			result.addEpsilon(p, q)
+		}
+	}
+
+	if len(accepting) == 1 {
+		result.initial = accepting[0]
+		return result
+	}
+	result.initial = result.addState(result.freshName("S"), false)
+	for _, q := range accepting {
+		result.addEpsilon(result.initial, q)
+	}
+	return result
+}
+
+// Star returns an FA accepting the Kleene star of the language of fa. A fresh
+// accepting initial state S accepts the empty string and leads to the
+// original initial state, to which every accepting state loops back.
+func Star(fa *FA) (*FA, error) {
+	a, err := compile(fa)
+	if err != nil {
+		return nil, err
+	}
+	a.loopBack()
+	return a.withOptional().toFA(), nil
+}
+
+// Plus returns an FA accepting the Kleene plus (one or more repetitions) of
+// the language of fa, by looping every accepting state back to the initial one.
+func Plus(fa *FA) (*FA, error) {
+	a, err := compile(fa)
+	if err != nil {
+		return nil, err
+	}
+	a.loopBack()
+	return a.toFA(), nil
+}
+
+// Optional returns an FA accepting the language of fa plus the empty string,
+// through a fresh accepting initial state S leading to the original one.
+func Optional(fa *FA) (*FA, error) {
+	a, err := compile(fa)
+	if err != nil {
+		return nil, err
+	}
+	return a.withOptional().toFA(), nil
+}
+
+// loopBack adds epsilon transitions from every accepting state to the initial state.
+func (a *automaton) loopBack() {
+	for q := range a.states {
+		if a.accept[q] {
+			a.addEpsilon(q, a.initial)
+		}
+	}
+}
+
+// withOptional adds a fresh accepting initial state with an epsilon transition
+// to the former initial state. A new state is needed so that strings leading
+// back to the former initial state are not accepted by mistake.
+func (a *automaton) withOptional() *automaton {
+	initial := a.addState(a.freshName("S"), true)
+	a.addEpsilon(initial, a.initial)
+	a.initial = initial
+	return a
+}
+
+// Complete returns an equivalent FA in which every state has a transition on
+// every symbol, missing ones leading to a non-accepting sink @t that loops on
+// every symbol. Complete FAs are returned unchanged.
+func Complete(fa *FA) (*FA, error) {
+	a, err := compile(fa)
+	if err != nil {
+		return nil, err
+	}
+	a.complete()
+	return a.toFA(), nil
+}
+
+// Trim returns an equivalent FA without the states that are unreachable from
+// the initial state or cannot reach an accepting one. The initial state is
+// kept even when the language is empty.
+func Trim(fa *FA) (*FA, error) {
+	a, err := compile(fa)
+	if err != nil {
+		return nil, err
+	}
+	return a.trim().toFA(), nil
+}
+
+// RemoveEpsilon returns an equivalent FA without epsilon transitions and with
+// the same states. Every state takes over the transitions of the states in its
+// epsilon closure and accepts if any of them does. Unlike NFAToDFA, the result
+// is usually still nondeterministic but never larger than the input.
+func RemoveEpsilon(fa *FA) (*FA, error) {
+	a, err := compile(fa)
+	if err != nil {
+		return nil, err
+	}
+	return a.removeEpsilon().toFA(), nil
+}
+
+// removeEpsilon builds the epsilon-free automaton over the same states.
+func (a *automaton) removeEpsilon() *automaton {
+	result := newAutomaton(a.alphabet)
+	for q, name := range a.states {
+		closure := newStateSet(len(a.states))
+		closure.add(q)
+		a.closure(closure)
+		result.addState(name, a.accepts(closure))
+		for symbol := range a.alphabet {
+			result.delta[q][symbol] = a.move(closure, symbol).members()
+		}
+	}
+	result.initial = a.initial
+	return result
+}
@@ -0,0 +1,153 @@
+package logic
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// Enumerate returns the words of length at most maxLen accepted by fa in
+// shortlex order: shorter words first, words of equal length ordered
+// lexicographically by the order of symbols in the alphabet. At most limit
+// words are returned; a limit of zero or less means no limit.
+func Enumerate(fa *FA, maxLen, limit int) ([]string, error) {
+	if maxLen < 0 {
+		return nil, fmt.Errorf("negative length %d", maxLen)
+	}
+	dfa, err := usefulDFA(fa)
+	if err != nil {
+		return nil, err
+	}
+	words := []string{}
+	if dfa == nil {
+		return words, nil
+	}
+
+	// The words of every length are spelled depth first in symbol order, only
+	// following transitions after which an accepted word of the remaining
+	// length exists, so that every path explored yields a word and the work
+	// stops with the limit
+	completable := dfa.completable(maxLen)
+	word := make([]string, 0, maxLen)
+	var spell func(q, remaining int) bool
+	spell = func(q, remaining int) bool {
+		if remaining == 0 {
+			words = append(words, strings.Join(word, ""))
+			return limit > 0 && len(words) >= limit
+		}
+		for symbol, name := range dfa.alphabet {
+			p := dfa.next(q, symbol)
+			if p < 0 || !completable[remaining-1][p] {
+				continue
+			}
+			word = append(word, name)
+			done := spell(p, remaining-1)
+			word = word[:len(word)-1]
+			if done {
+				return true
+			}
+		}
+		return false
+	}
+	for length := 0; length <= maxLen; length++ {
+		if completable[length][dfa.initial] && spell(dfa.initial, length) {
+			break
+		}
+	}
+	return words, nil
+}
+
+// completable returns, for every length k from 0 to n and every state q,
+// whether some word of length k leads from q to acceptance on a deterministic
+// automaton.
+func (a *automaton) completable(n int) [][]bool {
+	completable := make([][]bool, n+1)
+	for k := range completable {
+		completable[k] = make([]bool, len(a.states))
+		for q := range a.states {
+			if k == 0 {
+				completable[k][q] = a.accept[q]
+				continue
+			}
+			for symbol := range a.alphabet {
+				if p := a.next(q, symbol); p >= 0 && completable[k-1][p] {
+					completable[k][q] = true
+					break
+				}
+			}
+		}
+	}
+	return completable
+}
+
+// CountByLength returns, for every length from 0 to n, the exact number of
+// words of that length accepted by fa.
+func CountByLength(fa *FA, n int) ([]*big.Int, error) {
+	if n < 0 {
+		return nil, fmt.Errorf("negative length %d", n)
+	}
+	a, err := compile(fa)
+	if err != nil {
+		return nil, err
+	}
+	dfa, _ := determinize(a)
+	return dfa.countByLength(n), nil
+}
+
+// countByLength counts accepted words per length on a deterministic automaton
+// by propagating the number of paths reaching every state one symbol at a time.
+func (a *automaton) countByLength(n int) []*big.Int {
+	paths := make([]*big.Int, len(a.states))
+	for q := range paths {
+		paths[q] = new(big.Int)
+	}
+	paths[a.initial].SetInt64(1)
+
+	counts := make([]*big.Int, 0, n+1)
+	for length := 0; length <= n; length++ {
+		total := new(big.Int)
+		for q, count := range paths {
+			if a.accept[q] {
+				total.Add(total, count)
+			}
+		}
+		counts = append(counts, total)
+		if length == n {
+			break
+		}
+
+		next := make([]*big.Int, len(a.states))
+		for q := range next {
+			next[q] = new(big.Int)
+		}
+		for q, count := range paths {
+			if count.Sign() == 0 {
+				continue
+			}
+			for symbol := range a.alphabet {
+				if p := a.next(q, symbol); p >= 0 {
+					next[p].Add(next[p], count)
+				}
+			}
+		}
+		paths = next
+	}
+	return counts
+}
+
+// usefulDFA determinizes fa and keeps only the states lying on a path from the
+// initial state to an accepting one, so that every transition can be extended
+// into an accepted word. It returns nil when the language is empty.
+func usefulDFA(fa *FA) (*automaton, error) {
+	a, err := compile(fa)
+	if err != nil {
+		return nil, err
+	}
+	dfa, _ := determinize(a)
+
+	useful := dfa.useful()
+	if !useful[dfa.initial] {
+		return nil, nil
+	}
+	return dfa.restrict(useful), nil
+}
@@ -15,12 +15,28 @@ func main() {
 	r.HandleFunc("/n-boolean", handlers.NBooleanHandler).Methods("POST")
 	r.HandleFunc("/concatenation", handlers.ConcatenationHandler).Methods("POST")
 	r.HandleFunc("/complement", handlers.ComplementHandler).Methods("GET")
+	r.HandleFunc("/reverse", handlers.ReverseHandler).Methods("GET")
+	r.HandleFunc("/star", handlers.StarHandler).Methods("GET")
+	r.HandleFunc("/plus", handlers.PlusHandler).Methods("GET")
+	r.HandleFunc("/complete", handlers.CompleteHandler).Methods("GET")
+	r.HandleFunc("/trim", handlers.TrimHandler).Methods("GET")
+	r.HandleFunc("/remove-epsilon", handlers.RemoveEpsilonHandler).Methods("GET")
 	r.HandleFunc("/minimize-dfa", handlers.MinimizeDFAHandler).Methods("GET")
 	r.HandleFunc("/fa-to-regex", handlers.FAToRegexHandler).Methods("GET")
 	r.HandleFunc("/regex-to-nfa", handlers.RegexToNFAHandler).Methods("POST")
+	r.HandleFunc("/regex-to-dfa", handlers.RegexToDFAHandler).Methods("POST")
+	r.HandleFunc("/derivative", handlers.DerivativeHandler).Methods("POST")
 	r.HandleFunc("/nfa-to-dfa", handlers.NFAToDFAHandler).Methods("GET")
 	r.HandleFunc("/run-string", handlers.RunStringHandler).Methods("POST")
 
+	// Decision endpoints
+	r.HandleFunc("/equivalence", handlers.EquivalenceHandler).Methods("POST")
+	r.HandleFunc("/inclusion", handlers.InclusionHandler).Methods("POST")
+	r.HandleFunc("/disjointness", handlers.DisjointnessHandler).Methods("POST")
+	r.HandleFunc("/analyze", handlers.AnalyzeHandler).Methods("GET")
+	r.HandleFunc("/words", handlers.WordsHandler).Methods("GET")
+	r.HandleFunc("/sample", handlers.SampleHandler).Methods("POST")
+
 	// Storage endpoints
 	r.HandleFunc("/tex/{uuid}", handlers.GetTeXHandler).Methods("GET")
 	r.HandleFunc("/svg/{uuid}", handlers.GetSVGHandler).Methods("GET")
@@ -30,15 +46,29 @@ func main() {
 
 	log.Println("Backend running on :8080")
 	log.Println("Available endpoints:")
-	log.Println("  POST /boolean - Deterministic union/intersection of multiple FAs")
+	log.Println("  POST /boolean - Deterministic union/intersection/difference/symmetric difference or expression of multiple FAs")
 	log.Println("  POST /n-boolean - Non-deterministic union/intersection of multiple FAs")
 	log.Println("  POST /concatenation - Concatenation of multiple FAs")
 	log.Println("  GET  /complement?uuid=<uuid> - Complement of FA")
-	log.Println("  GET  /minimize-dfa?uuid=<uuid> - Minimize DFA")
-	log.Println("  GET  /fa-to-regex?uuid=<uuid> - Convert FA to regex")
-	log.Println("  POST /regex-to-nfa - Convert regex to NFA")
-	log.Println("  GET  /nfa-to-dfa?uuid=<uuid> - Convert NFA to DFA")
+	log.Println("  GET  /reverse?uuid=<uuid> - Reversal of FA")
+	log.Println("  GET  /star?uuid=<uuid> - Kleene star of FA")
+	log.Println("  GET  /plus?uuid=<uuid> - Kleene plus of FA")
+	log.Println("  GET  /complete?uuid=<uuid> - Complete FA with a sink state")
+	log.Println("  GET  /trim?uuid=<uuid> - Remove useless states of FA")
+	log.Println("  GET  /remove-epsilon?uuid=<uuid> - Remove epsilon transitions of NFA")
+	log.Println("  GET  /minimize-dfa?uuid=<uuid>&algorithm=<hopcroft|moore|brzozowski>&trace=<true|false> - Minimize DFA")
+	log.Println("  GET  /fa-to-regex?uuid=<uuid>&order=<array|edges|weight|custom>&states=<q1,q2,...>&raw=<true|false>&sizes=<true|false>&trace=<true|false> - Convert FA to simplified regex")
+	log.Println("  POST /regex-to-nfa - Convert regex to NFA (thompson, glushkov or antimirov)")
+	log.Println("  POST /regex-to-dfa - Convert regex to DFA using Brzozowski derivatives")
+	log.Println("  POST /derivative - Derivatives of a regex by every symbol of a word")
+	log.Println("  GET  /nfa-to-dfa?uuid=<uuid>&trace=<true|false> - Convert NFA to DFA")
 	log.Println("  POST /run-string - Run a string through an FA")
+	log.Println("  POST /equivalence - Check two FAs for language equality with a counterexample")
+	log.Println("  POST /inclusion - Check that the first FA's language is included in the second's")
+	log.Println("  POST /disjointness - Check that two FAs accept no common string")
+	log.Println("  GET  /analyze?uuid=<uuid> - Emptiness, finiteness and universality of an FA's language")
+	log.Println("  GET  /words?uuid=<uuid>&max_len=<n>&limit=<n> - Enumerate and count accepted words")
+	log.Println("  POST /sample - Draw uniformly random accepted/rejected strings of an FA")
 	log.Println("  POST /render - Render FA to SVG")
 	log.Println("  GET  /tex/{uuid} - Get saved TeX file")
 	log.Println("  GET  /svg/{uuid} - Get saved SVG file")